@@ -0,0 +1,243 @@
+// Package api serves an optional REST API on the loopback interface so other
+// tools can script go-sing. Every endpoint except the OpenAPI description
+// requires the bearer token from the settings dialog.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sing/vpn"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// ErrUnavailable is wrapped by Backend errors for requests that cannot be
+// served in the current state, e.g. starting before sing-box is downloaded.
+var ErrUnavailable = errors.New("unavailable")
+
+// Backend performs the actions behind the API, normally the running GUI.
+type Backend interface {
+	Status() vpn.Status
+	Start() error
+	Stop() error
+	Profiles() (current string, available []string)
+	SwitchProfile(name string) error
+	Channels() (current string, available []string)
+	SwitchChannel(name string) error
+	FetchConfig() error
+	Outbounds() ([]vpn.OutboundGroup, error)
+	Traffic() (*vpn.Traffic, error)
+}
+
+type Server struct {
+	backend Backend
+	token   string
+	server  *http.Server
+}
+
+func NewServer(backend Backend, token string) *Server {
+	return &Server{
+		backend: backend,
+		token:   token,
+	}
+}
+
+// Start listens on 127.0.0.1:port and serves in the background.
+func (s *Server) Start(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.server.Serve(listener)
+	return nil
+}
+
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.server.Shutdown(ctx)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/openapi.yaml", s.handleOpenAPI)
+	mux.Handle("GET /api/v1/status", s.authorized(s.handleStatus))
+	mux.Handle("POST /api/v1/start", s.authorized(s.handleStart))
+	mux.Handle("POST /api/v1/stop", s.authorized(s.handleStop))
+	mux.Handle("GET /api/v1/profiles", s.authorized(s.handleProfiles))
+	mux.Handle("PUT /api/v1/profiles/current", s.authorized(s.handleSwitchProfile))
+	mux.Handle("GET /api/v1/channels", s.authorized(s.handleChannels))
+	mux.Handle("PUT /api/v1/channels/current", s.authorized(s.handleSwitchChannel))
+	mux.Handle("POST /api/v1/fetch", s.authorized(s.handleFetch))
+	mux.Handle("GET /api/v1/outbounds", s.authorized(s.handleOutbounds))
+	mux.Handle("GET /api/v1/traffic", s.authorized(s.handleTraffic))
+	return loopbackOnly(mux)
+}
+
+// loopbackOnly rejects requests whose Host is not a loopback name, so web
+// pages cannot reach the API through DNS rebinding.
+func loopbackOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+
+		if host != "localhost" && host != "127.0.0.1" && host != "::1" {
+			writeError(w, http.StatusForbidden, errors.New("forbidden host"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backend.Status())
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	s.writeResult(w, s.backend.Start())
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.writeResult(w, s.backend.Stop())
+}
+
+// choicesResponse lists profiles or channels and the one in use.
+type choicesResponse struct {
+	Current   string   `json:"current"`
+	Available []string `json:"available"`
+}
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	current, available := s.backend.Profiles()
+	writeJSON(w, http.StatusOK, choicesResponse{Current: current, Available: available})
+}
+
+func (s *Server) handleSwitchProfile(w http.ResponseWriter, r *http.Request) {
+	name, ok := readName(w, r, "profile")
+	if !ok {
+		return
+	}
+
+	_, available := s.backend.Profiles()
+	if !slices.Contains(available, name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no profile called %q", name))
+		return
+	}
+
+	s.writeResult(w, s.backend.SwitchProfile(name))
+}
+
+func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
+	current, available := s.backend.Channels()
+	writeJSON(w, http.StatusOK, choicesResponse{Current: current, Available: available})
+}
+
+func (s *Server) handleSwitchChannel(w http.ResponseWriter, r *http.Request) {
+	name, ok := readName(w, r, "channel")
+	if !ok {
+		return
+	}
+
+	_, available := s.backend.Channels()
+	if !slices.Contains(available, name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("channel %q is not offered by the delivery config", name))
+		return
+	}
+
+	s.writeResult(w, s.backend.SwitchChannel(name))
+}
+
+// readName reads a {"name": ...} request body, answering 400 if it is not one.
+func readName(w http.ResponseWriter, r *http.Request, what string) (string, bool) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&request)
+	if err != nil || request.Name == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`expected {"name": "<%s>"}`, what))
+		return "", false
+	}
+	return request.Name, true
+}
+
+func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
+	s.writeResult(w, s.backend.FetchConfig())
+}
+
+func (s *Server) handleOutbounds(w http.ResponseWriter, r *http.Request) {
+	outbounds, err := s.backend.Outbounds()
+	if err != nil {
+		s.writeResult(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, outbounds)
+}
+
+func (s *Server) handleTraffic(w http.ResponseWriter, r *http.Request) {
+	traffic, err := s.backend.Traffic()
+	if err != nil {
+		s.writeResult(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, traffic)
+}
+
+// writeResult answers an action with the current status, or maps err to an
+// HTTP status.
+func (s *Server) writeResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, s.backend.Status())
+	case errors.Is(err, ErrUnavailable), errors.Is(err, vpn.ErrClashAPIDisabled):
+		writeError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
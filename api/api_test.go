@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sing/vpn"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "secret-token"
+
+type fakeBackend struct {
+	running   bool
+	profile   string
+	profiles  []string
+	channel   string
+	channels  []string
+	startErr  error
+	fetched   int
+	outbounds []vpn.OutboundGroup
+	traffic   *vpn.Traffic
+	clashErr  error
+}
+
+func (b *fakeBackend) Status() vpn.Status {
+	return vpn.Status{ClientVersion: "1.0.0", Running: b.running, Channel: b.channel}
+}
+
+func (b *fakeBackend) Start() error {
+	if b.startErr != nil {
+		return b.startErr
+	}
+	b.running = true
+	return nil
+}
+
+func (b *fakeBackend) Stop() error {
+	b.running = false
+	return nil
+}
+
+func (b *fakeBackend) Profiles() (string, []string) {
+	return b.profile, b.profiles
+}
+
+func (b *fakeBackend) SwitchProfile(name string) error {
+	b.profile = name
+	return nil
+}
+
+func (b *fakeBackend) Channels() (string, []string) {
+	return b.channel, b.channels
+}
+
+func (b *fakeBackend) SwitchChannel(name string) error {
+	b.channel = name
+	return nil
+}
+
+func (b *fakeBackend) FetchConfig() error {
+	b.fetched++
+	return nil
+}
+
+func (b *fakeBackend) Outbounds() ([]vpn.OutboundGroup, error) {
+	return b.outbounds, b.clashErr
+}
+
+func (b *fakeBackend) Traffic() (*vpn.Traffic, error) {
+	return b.traffic, b.clashErr
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		profile:  "Home",
+		profiles: []string{"Home", "Work"},
+		channel:  "stable",
+		channels: []string{"beta", "stable"},
+	}
+}
+
+func serve(backend Backend, method, path, body string, configure func(r *http.Request)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Host = "127.0.0.1:29583"
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	if configure != nil {
+		configure(r)
+	}
+
+	w := httptest.NewRecorder()
+	NewServer(backend, testToken).Handler().ServeHTTP(w, r)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid token", "Bearer " + testToken, http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + testToken, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(newFakeBackend(), "GET", "/api/v1/status", "", func(r *http.Request) {
+				r.Header.Set("Authorization", tt.header)
+			})
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectsNonLoopbackHost(t *testing.T) {
+	for _, host := range []string{"evil.example.com", "evil.example.com:29583", "192.168.1.10:29583"} {
+		w := serve(newFakeBackend(), "GET", "/api/v1/status", "", func(r *http.Request) {
+			r.Host = host
+		})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("host %q: got status %d, want %d", host, w.Code, http.StatusForbidden)
+		}
+	}
+
+	for _, host := range []string{"localhost:29583", "[::1]:29583", "127.0.0.1"} {
+		w := serve(newFakeBackend(), "GET", "/api/v1/status", "", func(r *http.Request) {
+			r.Host = host
+		})
+		if w.Code != http.StatusOK {
+			t.Errorf("host %q: got status %d, want %d", host, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestOpenAPIDoesNotNeedToken(t *testing.T) {
+	w := serve(newFakeBackend(), "GET", "/api/v1/openapi.yaml", "", func(r *http.Request) {
+		r.Header.Del("Authorization")
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if !strings.HasPrefix(w.Body.String(), "openapi: 3") {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestStatus(t *testing.T) {
+	backend := newFakeBackend()
+	backend.running = true
+
+	w := serve(backend, "GET", "/api/v1/status", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	status := decode[vpn.Status](t, w)
+	if !status.Running || status.Channel != "stable" {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	backend := newFakeBackend()
+
+	w := serve(backend, "POST", "/api/v1/start", "", nil)
+	if w.Code != http.StatusOK || !decode[vpn.Status](t, w).Running {
+		t.Fatalf("start: got status %d, running %t", w.Code, backend.running)
+	}
+
+	w = serve(backend, "POST", "/api/v1/stop", "", nil)
+	if w.Code != http.StatusOK || decode[vpn.Status](t, w).Running {
+		t.Fatalf("stop: got status %d, running %t", w.Code, backend.running)
+	}
+
+	w = serve(backend, "GET", "/api/v1/start", "", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET start: got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: sing-box is not available yet", ErrUnavailable), http.StatusServiceUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		backend := newFakeBackend()
+		backend.startErr = tt.err
+
+		w := serve(backend, "POST", "/api/v1/start", "", nil)
+		if w.Code != tt.want {
+			t.Errorf("%v: got status %d, want %d", tt.err, w.Code, tt.want)
+		}
+		if body := decode[map[string]string](t, w); body["error"] != tt.err.Error() {
+			t.Errorf("got error %q, want %q", body["error"], tt.err.Error())
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	backend := newFakeBackend()
+
+	w := serve(backend, "GET", "/api/v1/profiles", "", nil)
+	profiles := decode[choicesResponse](t, w)
+	if profiles.Current != "Home" || len(profiles.Available) != 2 {
+		t.Errorf("unexpected profiles %+v", profiles)
+	}
+
+	w = serve(backend, "PUT", "/api/v1/profiles/current", `{"name": "Work"}`, nil)
+	if w.Code != http.StatusOK || backend.profile != "Work" {
+		t.Errorf("switch: got status %d, profile %q", w.Code, backend.profile)
+	}
+
+	w = serve(backend, "PUT", "/api/v1/profiles/current", `{"name": "Cafe"}`, nil)
+	if w.Code != http.StatusNotFound || backend.profile != "Work" {
+		t.Errorf("unknown profile: got status %d, profile %q", w.Code, backend.profile)
+	}
+
+	w = serve(backend, "PUT", "/api/v1/profiles/current", `{}`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad body: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	backend := newFakeBackend()
+
+	w := serve(backend, "GET", "/api/v1/channels", "", nil)
+	channels := decode[choicesResponse](t, w)
+	if channels.Current != "stable" || len(channels.Available) != 2 {
+		t.Errorf("unexpected channels %+v", channels)
+	}
+
+	w = serve(backend, "PUT", "/api/v1/channels/current", `{"name": "beta"}`, nil)
+	if w.Code != http.StatusOK || backend.channel != "beta" {
+		t.Errorf("switch: got status %d, channel %q", w.Code, backend.channel)
+	}
+
+	w = serve(backend, "PUT", "/api/v1/channels/current", `{"name": "nightly"}`, nil)
+	if w.Code != http.StatusNotFound || backend.channel != "beta" {
+		t.Errorf("unknown channel: got status %d, channel %q", w.Code, backend.channel)
+	}
+
+	w = serve(backend, "PUT", "/api/v1/channels/current", `beta`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad body: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	backend := newFakeBackend()
+
+	w := serve(backend, "POST", "/api/v1/fetch", "", nil)
+	if w.Code != http.StatusOK || backend.fetched != 1 {
+		t.Errorf("got status %d, fetched %d times", w.Code, backend.fetched)
+	}
+}
+
+func TestOutboundsAndTraffic(t *testing.T) {
+	backend := newFakeBackend()
+	backend.outbounds = []vpn.OutboundGroup{{Name: "proxy", Type: "Selector", Now: "server-1"}}
+	backend.traffic = &vpn.Traffic{UploadTotal: 10, DownloadTotal: 20, ActiveConnections: 1}
+
+	w := serve(backend, "GET", "/api/v1/outbounds", "", nil)
+	outbounds := decode[[]vpn.OutboundGroup](t, w)
+	if len(outbounds) != 1 || outbounds[0].Now != "server-1" {
+		t.Errorf("unexpected outbounds %+v", outbounds)
+	}
+
+	w = serve(backend, "GET", "/api/v1/traffic", "", nil)
+	if traffic := decode[vpn.Traffic](t, w); traffic != *backend.traffic {
+		t.Errorf("got traffic %+v, want %+v", traffic, *backend.traffic)
+	}
+
+	backend.clashErr = vpn.ErrClashAPIDisabled
+	for _, path := range []string{"/api/v1/outbounds", "/api/v1/traffic"} {
+		w = serve(backend, "GET", path, "", nil)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: got status %d, want %d", path, w.Code, http.StatusServiceUnavailable)
+		}
+	}
+}
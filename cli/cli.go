@@ -0,0 +1,85 @@
+// Package cli runs go-sing without the GUI, for servers and scripts.
+package cli
+
+import (
+	"fmt"
+	"go-sing/config"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]command{
+	"diagnostics":    {"diagnostics [-o FILE] [-hide-servers]  write a redacted zip of config, logs and system details for bug reports", runDiagnostics},
+	"fetch":          {"fetch [-url URL]  download the sing-box config from the subscription URL, saving a new URL as a profile", runFetch},
+	"profiles":       {"profiles  list the saved subscription profiles, marking the one in use", runProfiles},
+	"start":          {"start [-foreground]  start sing-box, in the background unless -foreground", runStart},
+	"stop":           {"stop  stop any running sing-box", runStop},
+	"status":         {"status [-json]  show client and sing-box state", runStatus},
+	"switch-channel": {"switch-channel <name>  use sing-box from another release channel", runSwitchChannel},
+	"switch-profile": {"switch-profile <name>  fetch and use another saved subscription profile", runSwitchProfile},
+	"update-core":    {"update-core [-force]  download sing-box if missing or outdated", runUpdateCore},
+}
+
+// IsCommand reports whether name is a CLI command rather than a GUI flag.
+func IsCommand(name string) bool {
+	if name == "help" || name == "-h" || name == "--help" {
+		return true
+	}
+	_, ok := commands[name]
+	return ok
+}
+
+// Run executes the command in args[0] and returns the process exit code.
+func Run(args []string) int {
+	attachConsole()
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		printUsage(os.Stdout)
+		return 0
+	}
+	return cmd.run(args[1:])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-sing <command> [flags]")
+	fmt.Fprintln(w, "Without a command go-sing starts the GUI.")
+	fmt.Fprintln(w)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+}
+
+// consoleLogger implements the Logger interfaces of config, vpn and updater
+// by writing timestamped, redacted lines.
+type consoleLogger struct {
+	w io.Writer
+}
+
+func newConsoleLogger(w io.Writer) *consoleLogger {
+	return &consoleLogger{w: w}
+}
+
+func (l *consoleLogger) Log(message string) {
+	timestamp := time.Now().Format("15:04:05")
+	fmt.Fprintf(l.w, "[%s] %s\n", timestamp, config.RedactSecrets(message))
+}
+
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return 1
+}
@@ -0,0 +1,442 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-sing/config"
+	"go-sing/internal/ipc"
+	"go-sing/vpn"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const readyTimeout = 5 * time.Minute
+
+func setup(logger *consoleLogger) (*config.Fetcher, *vpn.Controller, error) {
+	paths, err := config.ResolvePaths()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fetcher := config.NewFetcher(paths)
+	controller := vpn.NewController(logger, fetcher)
+
+	appConfig, err := fetcher.LoadAppConfig()
+	if err == nil {
+		controller.ApplySettings(appConfig.Settings)
+	}
+
+	return fetcher, controller, nil
+}
+
+// forward sends the command to a running go-sing instance. handled is false
+// when there is none and the command should run in this process.
+func forward(command string, args []string, result any) (handled bool, err error) {
+	paths, err := config.ResolvePaths()
+	if err != nil {
+		return false, err
+	}
+
+	err = ipc.Call(paths, command, args, result)
+	if errors.Is(err, ipc.ErrNotRunning) {
+		return false, nil
+	}
+	return true, err
+}
+
+func runFetch(args []string) int {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	url := flags.String("url", "", "subscription URL to fetch and remember (default: the saved one)")
+	flags.Parse(args)
+
+	logger := newConsoleLogger(os.Stdout)
+	fetcher, _, err := setup(logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := fetchSubscription(fetcher, logger, *url); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func fetchSubscription(fetcher *config.Fetcher, logger *consoleLogger, url string) error {
+	if url == "" {
+		appConfig, err := fetcher.LoadAppConfig()
+		if err != nil {
+			return err
+		}
+		url = appConfig.SubscriptionURL
+	}
+	if url == "" {
+		return fmt.Errorf("no subscription URL saved, pass -url")
+	}
+
+	logger.Log("Fetching configuration from: " + config.RedactURL(url))
+	_, err := fetcher.FetchConfig(url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config: %w", err)
+	}
+
+	_, err = fetcher.AddProfile("", url)
+	if err != nil {
+		return fmt.Errorf("failed to save subscription URL: %w", err)
+	}
+
+	logger.Log("Configuration updated successfully")
+	return nil
+}
+
+func runStart(args []string) int {
+	flags := flag.NewFlagSet("start", flag.ExitOnError)
+	foreground := flags.Bool("foreground", false, "keep running and stop sing-box on Ctrl+C")
+	flags.Parse(args)
+
+	handled, err := forward("start", nil, nil)
+	if handled {
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Println("Started by the running go-sing instance")
+		return 0
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	logger := newConsoleLogger(os.Stdout)
+	fetcher, controller, err := setup(logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	if _, err := os.Stat(fetcher.Paths().SingBoxConfig()); os.IsNotExist(err) {
+		if err := fetchSubscription(fetcher, logger, ""); err != nil {
+			return fail(err)
+		}
+	}
+
+	if !*foreground {
+		if _, err := os.Stat(fetcher.Paths().SingBoxExe()); os.IsNotExist(err) {
+			if err := controller.UpdateCore(false); err != nil {
+				return fail(err)
+			}
+		}
+		if err := controller.StartDetached(); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+
+	controller.StartMonitoring()
+	defer controller.Stop()
+
+	err = waitUntilReady(controller)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = controller.StartVPN()
+	if err != nil {
+		return fail(err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-signals:
+			logger.Log("Stopping...")
+			return 0
+		case <-ticker.C:
+			if !controller.IsRunning() && !controller.IsRestarting() {
+				logger.Log("sing-box is no longer running")
+				return 1
+			}
+		}
+	}
+}
+
+func waitUntilReady(controller *vpn.Controller) error {
+	deadline := time.Now().Add(readyTimeout)
+	for !controller.IsSingBoxAvailable() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("sing-box did not become available within %s", readyTimeout)
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return nil
+}
+
+func runStop(args []string) int {
+	flags := flag.NewFlagSet("stop", flag.ExitOnError)
+	flags.Parse(args)
+
+	handled, err := forward("stop", nil, nil)
+	if handled {
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Println("Stopped by the running go-sing instance")
+		return 0
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	logger := newConsoleLogger(os.Stdout)
+	_, controller, err := setup(logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := controller.StopAll(); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print status as JSON")
+	flags.Parse(args)
+
+	var status vpn.Status
+	handled, err := forward("status", nil, &status)
+	if err != nil {
+		return fail(err)
+	}
+	if !handled {
+		_, controller, err := setup(newConsoleLogger(os.Stderr))
+		if err != nil {
+			return fail(err)
+		}
+		status = controller.Status()
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(status); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+
+	fmt.Printf("go-sing:        %s\n", status.ClientVersion)
+	fmt.Printf("running:        %t\n", status.Running)
+	fmt.Printf("sing-box:       %s (installed: %t)\n", status.SingBoxVersion, status.SingBoxInstalled)
+	fmt.Printf("profile:        %s\n", status.Profile)
+	fmt.Printf("channel:        %s\n", status.Channel)
+	fmt.Printf("config present: %t\n", status.ConfigPresent)
+	fmt.Printf("subscription:   %s\n", status.Subscription)
+	fmt.Printf("data dir:       %s (portable: %t)\n", status.DataDir, status.Portable)
+	return 0
+}
+
+func runProfiles(args []string) int {
+	flags := flag.NewFlagSet("profiles", flag.ExitOnError)
+	flags.Parse(args)
+
+	var current string
+	var names []string
+
+	var result struct {
+		Current   string   `json:"current"`
+		Available []string `json:"available"`
+	}
+	handled, err := forward("profiles", nil, &result)
+	if err != nil {
+		return fail(err)
+	}
+	if handled {
+		current, names = result.Current, result.Available
+	} else {
+		fetcher, _, err := setup(newConsoleLogger(os.Stderr))
+		if err != nil {
+			return fail(err)
+		}
+		profiles, inUse, err := fetcher.Profiles()
+		if err != nil {
+			return fail(err)
+		}
+		current = inUse
+		for _, profile := range profiles {
+			names = append(names, profile.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		fmt.Println("No profiles saved, run fetch -url URL to add one")
+		return 0
+	}
+	for _, name := range names {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+	return 0
+}
+
+func runSwitchProfile(args []string) int {
+	flags := flag.NewFlagSet("switch-profile", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go-sing switch-profile <name>")
+		return 2
+	}
+	name := flags.Arg(0)
+
+	handled, err := forward("switch-profile", []string{name}, nil)
+	if handled {
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("The running go-sing instance switched to the %s profile\n", name)
+		return 0
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	logger := newConsoleLogger(os.Stdout)
+	fetcher, controller, err := setup(logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	profiles, current, err := fetcher.Profiles()
+	if err != nil {
+		return fail(err)
+	}
+	if name == current {
+		logger.Log("Already using the " + name + " profile")
+		return 0
+	}
+
+	index := slices.IndexFunc(profiles, func(p config.Profile) bool { return p.Name == name })
+	if index < 0 {
+		return fail(fmt.Errorf("no profile called %q", name))
+	}
+
+	// Fetched first, so a failing subscription leaves the current one in use
+	logger.Log("Fetching configuration from: " + config.RedactURL(profiles[index].URL))
+	if _, err := fetcher.FetchConfig(profiles[index].URL); err != nil {
+		return fail(fmt.Errorf("failed to fetch config: %w", err))
+	}
+	if _, err := fetcher.SwitchProfile(name); err != nil {
+		return fail(err)
+	}
+
+	logger.Log("Switched to the " + name + " profile")
+	if controller.IsSingBoxRunning() {
+		logger.Log("sing-box is running, restart it with stop and start to use the new profile")
+	}
+	return 0
+}
+
+func runSwitchChannel(args []string) int {
+	flags := flag.NewFlagSet("switch-channel", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go-sing switch-channel <name>")
+		return 2
+	}
+	channel := flags.Arg(0)
+
+	handled, err := forward("switch-channel", []string{channel}, nil)
+	if handled {
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("The running go-sing instance is switching to the %s channel\n", channel)
+		return 0
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	logger := newConsoleLogger(os.Stdout)
+	fetcher, _, err := setup(logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	deliveryConfig, err := fetcher.FetchDeliveryConfig()
+	if err != nil {
+		return fail(fmt.Errorf("failed to fetch delivery config: %w", err))
+	}
+	if _, err := deliveryConfig.Channel(channel); err != nil {
+		return fail(err)
+	}
+
+	if err := fetcher.SetChannel(channel); err != nil {
+		return fail(err)
+	}
+	logger.Log(fmt.Sprintf("Switched to the %s channel, run update-core to download it", channel))
+	return 0
+}
+
+func runUpdateCore(args []string) int {
+	flags := flag.NewFlagSet("update-core", flag.ExitOnError)
+	force := flags.Bool("force", false, "download even if the installed version is current")
+	flags.Parse(args)
+
+	logger := newConsoleLogger(os.Stdout)
+	_, controller, err := setup(logger)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := controller.UpdateCore(*force); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func runDiagnostics(args []string) int {
+	flags := flag.NewFlagSet("diagnostics", flag.ExitOnError)
+	output := flags.String("o", "", "zip file to write (default: go-sing-diagnostics-<time>.zip in the current directory)")
+	hideServers := flags.Bool("hide-servers", false, "also mask server addresses")
+	flags.Parse(args)
+
+	path := *output
+	if path == "" {
+		path = vpn.DiagnosticsFileName(time.Now())
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	// The running instance also knows its recent state changes
+	handled, err := forward("diagnostics", []string{path, strconv.FormatBool(*hideServers)}, nil)
+	if !handled {
+		logger := newConsoleLogger(os.Stderr)
+		var controller *vpn.Controller
+		_, controller, err = setup(logger)
+		if err == nil {
+			err = controller.ExportDiagnostics(path, *hideServers)
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Println(path)
+	return 0
+}
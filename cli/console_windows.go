@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	kernel32          = syscall.NewLazyDLL("kernel32.dll")
+	procAttachConsole = kernel32.NewProc("AttachConsole")
+)
+
+const ATTACH_PARENT_PROCESS = ^uintptr(0)
+
+// attachConsole connects stdout and stderr to the console of the shell that
+// started go-sing, since GUI subsystem builds do not get one of their own.
+func attachConsole() {
+	ret, _, _ := procAttachConsole.Call(ATTACH_PARENT_PROCESS)
+	if ret == 0 {
+		return
+	}
+
+	console, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	os.Stdout = console
+	os.Stderr = console
+}
@@ -0,0 +1,137 @@
+// Command sign-delivery signs a delivery config for distribution.
+//
+// Generate a key pair once, compile the public key into the client
+// (config.DeliveryPublicKey) and keep the private key file secret:
+//
+//	go run ./cmd/sign-delivery -genkey -key delivery.key
+//
+// Sign the config and host the resulting .sig next to it:
+//
+//	go run ./cmd/sign-delivery -key delivery.key delivery/delivery_config.json
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-sing/config"
+	"log"
+	"os"
+)
+
+func main() {
+	keyPath := flag.String("key", "", "path to the base64 encoded ed25519 private key seed")
+	genKey := flag.Bool("genkey", false, "generate a new key pair, write the private key to -key (which must not exist) and print the public key")
+	flag.Parse()
+
+	if *keyPath == "" {
+		log.Fatal("-key is required")
+	}
+
+	if *genKey {
+		if err := generateKey(*keyPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.NArg() != 1 {
+		log.Fatal("usage: sign-delivery -key <private key> <delivery_config.json>")
+	}
+
+	if err := signFile(*keyPath, flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generateKey(keyPath string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	// Never replace an existing key, which shipped clients already trust
+	file, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create private key file: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(privateKey.Seed())
+	_, err = file.WriteString(encoded + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(keyPath)
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	fmt.Println(base64.StdEncoding.EncodeToString(publicKey))
+	return nil
+}
+
+func signFile(keyPath, configPath string) error {
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	privateKey, err := config.DecodePrivateKey(string(keyData))
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read delivery config: %w", err)
+	}
+
+	err = checkChecksums(data)
+	if err != nil {
+		return err
+	}
+
+	signature := config.SignDeliveryConfig(data, privateKey)
+	sigPath := configPath + ".sig"
+	err = os.WriteFile(sigPath, []byte(signature+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	fmt.Printf("Wrote %s\n", sigPath)
+	return nil
+}
+
+// checkChecksums refuses configs that would let clients install a sing-box
+// zip or a client build without verifying it.
+func checkChecksums(data []byte) error {
+	var deliveryConfig config.DeliveryConfig
+	err := json.Unmarshal(data, &deliveryConfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse delivery config: %w", err)
+	}
+
+	for _, name := range deliveryConfig.ChannelNames() {
+		channel, err := deliveryConfig.Channel(name)
+		if err != nil {
+			return err
+		}
+		if channel.SingBoxZipSHA256 == "" {
+			return fmt.Errorf("channel %q has no sing_box_zip_sha256", channel.Name)
+		}
+	}
+
+	if client := deliveryConfig.Client; client != nil {
+		if client.URL != "" && client.SHA256 == "" {
+			return fmt.Errorf("client release has no sha256")
+		}
+		for platform, asset := range client.Assets {
+			if asset.SHA256 == "" {
+				return fmt.Errorf("client asset %q has no sha256", platform)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Announcement is a distributor message shown to users once per ID.
+type Announcement struct {
+	ID       string     `json:"id"`
+	Severity string     `json:"severity,omitempty"`
+	Text     string     `json:"text"`
+	Link     string     `json:"link,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+func (a Announcement) Expired(now time.Time) bool {
+	return a.Expires != nil && now.After(*a.Expires)
+}
+
+// ActiveAnnouncements returns the announcements that have an ID and have not expired.
+func (d *DeliveryConfig) ActiveAnnouncements(now time.Time) []Announcement {
+	var active []Announcement
+	for _, announcement := range d.Announcements {
+		if announcement.ID == "" || announcement.Expired(now) {
+			continue
+		}
+		active = append(active, announcement)
+	}
+	return active
+}
+
+// ClientUpdateRequired reports whether the running client is older than the
+// minimum version the delivery config allows to connect.
+func (d *DeliveryConfig) ClientUpdateRequired() bool {
+	return ClientVersionBelow(d.MinClientVersion)
+}
+
+// ClientVersionBelow reports whether the running client is older than
+// minVersion. An empty minVersion sets no minimum.
+func ClientVersionBelow(minVersion string) bool {
+	if minVersion == "" {
+		return false
+	}
+	return CompareVersions(ClientVersion, minVersion) < 0
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-sing/internal/secrets"
+)
+
+const apiTokenSecret = "api_token"
+
+// APIToken returns the bearer token for the local REST API, generating and
+// storing one on first use.
+func (f *Fetcher) APIToken() (string, error) {
+	token, err := f.secrets.Get(apiTokenSecret)
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+
+	return f.ResetAPIToken()
+}
+
+// ResetAPIToken replaces the API token, locking out existing scripts.
+func (f *Fetcher) ResetAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	err = f.secrets.Set(apiTokenSecret, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to store API token: %w", err)
+	}
+	return token, nil
+}
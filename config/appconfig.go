@@ -0,0 +1,328 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sing/internal/secrets"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// AppConfigSchemaVersion is the app_config.json layout written by this build.
+// Bump it together with a new entry in appConfigMigrations.
+const AppConfigSchemaVersion = 1
+
+type AppConfig struct {
+	SchemaVersion         int      `json:"schema_version"`
+	SubscriptionURL       string   `json:"subscription_url,omitempty"`
+	CurrentSingBoxVersion string   `json:"current_sing_box_version"`
+	Channel               string   `json:"channel,omitempty"`
+	SkippedClientVersion  string   `json:"skipped_client_version,omitempty"`
+	MinClientVersion      string   `json:"min_client_version,omitempty"`
+	SeenAnnouncements     []string `json:"seen_announcements,omitempty"`
+	Profile               string   `json:"profile,omitempty"`
+	Profiles              []string `json:"profiles,omitempty"`
+	Settings              Settings `json:"settings"`
+}
+
+type appConfigMigration func(f *Fetcher, raw map[string]json.RawMessage) error
+
+// appConfigMigrations[i] upgrades a raw app config from schema version i to i+1.
+var appConfigMigrations = []appConfigMigration{
+	migrateSubscriptionURLToSecrets,
+}
+
+// migrateSubscriptionURLToSecrets moves the plain text subscription URL
+// written by older versions into the secret store.
+func migrateSubscriptionURLToSecrets(f *Fetcher, raw map[string]json.RawMessage) error {
+	value, ok := raw["subscription_url"]
+	if !ok {
+		return nil
+	}
+
+	var url string
+	err := json.Unmarshal(value, &url)
+	if err != nil {
+		return fmt.Errorf("failed to parse subscription_url: %w", err)
+	}
+
+	if url != "" {
+		err = f.secrets.Set(subscriptionURLSecret, url)
+		if err != nil {
+			return fmt.Errorf("failed to store subscription URL: %w", err)
+		}
+	}
+
+	delete(raw, "subscription_url")
+	return nil
+}
+
+// LoadAppConfig returns the stored app config, with the default subscription
+// URL of the delivery config when none is stored.
+func (f *Fetcher) LoadAppConfig() (*AppConfig, error) {
+	appConfig, err := f.loadStoredAppConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetched without the lock held, as it goes to the network
+	if appConfig.SubscriptionURL == "" {
+		appConfig.SubscriptionURL = f.getDefaultSubscriptionURL()
+	}
+	return appConfig, nil
+}
+
+// loadStoredAppConfig returns the app config as stored, without reaching out
+// to the network.
+func (f *Fetcher) loadStoredAppConfig() (*AppConfig, error) {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	return f.loadAppConfig()
+}
+
+func (f *Fetcher) SaveAppConfig(appConfig *AppConfig) error {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	return f.saveAppConfig(appConfig)
+}
+
+// UpdateAppConfig applies update to the stored app config as a single
+// read-modify-write, so concurrent updates of different fields are not lost.
+func (f *Fetcher) UpdateAppConfig(update func(appConfig *AppConfig) error) error {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	appConfig, err := f.loadAppConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load app config: %w", err)
+	}
+
+	err = update(appConfig)
+	if err != nil {
+		return err
+	}
+
+	return f.saveAppConfig(appConfig)
+}
+
+func (f *Fetcher) EnsureAppConfigExists() error {
+	if _, err := os.Stat(f.paths.AppConfig()); err == nil {
+		return nil
+	}
+
+	defaultURL := f.getDefaultSubscriptionURL()
+	if defaultURL == "" {
+		return nil
+	}
+
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	if _, err := os.Stat(f.paths.AppConfig()); err == nil {
+		return nil
+	}
+	return f.saveAppConfig(&AppConfig{SubscriptionURL: defaultURL})
+}
+
+func (f *Fetcher) loadAppConfig() (*AppConfig, error) {
+	raw, err := f.readRawAppConfig()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &AppConfig{
+				SchemaVersion: AppConfigSchemaVersion,
+				Settings:      DefaultSettings(),
+			}, nil
+		}
+		return nil, err
+	}
+
+	err = f.migrateAppConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal app config: %w", err)
+	}
+
+	var appConfig AppConfig
+	err = json.Unmarshal(data, &appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app config: %w", err)
+	}
+
+	appConfig.Settings = appConfig.Settings.WithDefaults().Sanitized()
+
+	appConfig.SubscriptionURL, err = f.secrets.Get(subscriptionURLSecret)
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return nil, fmt.Errorf("failed to read subscription URL: %w", err)
+	}
+
+	return &appConfig, nil
+}
+
+// migrateAppConfig runs the pending migrations on raw and persists the result,
+// keeping a backup of the file as it was before.
+func (f *Fetcher) migrateAppConfig(raw map[string]json.RawMessage) error {
+	version := 0
+	if value, ok := raw["schema_version"]; ok {
+		err := json.Unmarshal(value, &version)
+		if err != nil {
+			return fmt.Errorf("failed to parse schema_version: %w", err)
+		}
+	}
+
+	// Written by a newer build, use what we understand and leave the rest alone
+	if version >= AppConfigSchemaVersion {
+		return nil
+	}
+
+	// The backup leaves out the subscription URL, which belongs in the secret store
+	backup := make(map[string]json.RawMessage, len(raw))
+	for key, value := range raw {
+		if key != "subscription_url" {
+			backup[key] = value
+		}
+	}
+
+	appConfigPath := f.paths.AppConfig()
+	backupPath := fmt.Sprintf("%s.v%d.bak", appConfigPath, version)
+	err := writeRawAppConfig(backupPath, backup)
+	if err != nil {
+		return fmt.Errorf("failed to back up app config: %w", err)
+	}
+
+	for ; version < AppConfigSchemaVersion; version++ {
+		err = appConfigMigrations[version](f, raw)
+		if err != nil {
+			return fmt.Errorf("failed to migrate app config to schema version %d: %w", version+1, err)
+		}
+	}
+
+	raw["schema_version"] = json.RawMessage(fmt.Sprint(AppConfigSchemaVersion))
+	return writeRawAppConfig(appConfigPath, raw)
+}
+
+func (f *Fetcher) saveAppConfig(appConfig *AppConfig) error {
+	var err error
+	if appConfig.SubscriptionURL != "" {
+		err = f.secrets.Set(subscriptionURLSecret, appConfig.SubscriptionURL)
+	} else {
+		err = f.secrets.Delete(subscriptionURLSecret)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store subscription URL: %w", err)
+	}
+
+	stored := *appConfig
+	stored.SubscriptionURL = ""
+	if stored.SchemaVersion < AppConfigSchemaVersion {
+		stored.SchemaVersion = AppConfigSchemaVersion
+	}
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app config: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app config: %w", err)
+	}
+
+	// Keep fields this build does not know about, e.g. written by a newer one
+	raw, err := f.readRawAppConfig()
+	if err != nil {
+		raw = make(map[string]json.RawMessage)
+	}
+	for _, key := range appConfigFields() {
+		delete(raw, key)
+	}
+	for key, value := range fields {
+		raw[key] = value
+	}
+
+	err = writeRawAppConfig(f.paths.AppConfig(), raw)
+	if err != nil {
+		return fmt.Errorf("failed to save app config: %w", err)
+	}
+
+	return nil
+}
+
+func (f *Fetcher) readRawAppConfig() (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(f.paths.AppConfig())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read app config: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app config: %w", err)
+	}
+	if raw == nil {
+		raw = make(map[string]json.RawMessage)
+	}
+	return raw, nil
+}
+
+func writeRawAppConfig(path string, raw map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal app config: %w", err)
+	}
+	return writePrivateFile(path, data)
+}
+
+// appConfigFields lists the JSON keys owned by AppConfig.
+func appConfigFields() []string {
+	var keys []string
+	appConfigType := reflect.TypeOf(AppConfig{})
+	for i := 0; i < appConfigType.NumField(); i++ {
+		tag := appConfigType.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// writePrivateFile atomically replaces path with data readable by the current
+// user only, so a crash mid-write never leaves a truncated file behind.
+func writePrivateFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpPath, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-sing/internal/secrets"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deliveryTransport serves a delivery config and its signature in place of
+// the real delivery URLs, answering 404 for a nil one.
+type deliveryTransport struct {
+	config, signature []byte
+}
+
+func (d deliveryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data := d.config
+	if strings.HasSuffix(req.URL.Path, ".sig") {
+		data = d.signature
+	}
+
+	status := http.StatusOK
+	if data == nil {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}, nil
+}
+
+// repoDeliveryConfig reads the signed delivery config checked into the repo.
+func repoDeliveryConfig(t *testing.T) (config, signature []byte) {
+	t.Helper()
+
+	config, err := os.ReadFile(filepath.Join("..", "delivery", "delivery_config.json"))
+	if err != nil {
+		t.Fatalf("failed to read delivery config: %v", err)
+	}
+	signature, err = os.ReadFile(filepath.Join("..", "delivery", "delivery_config.json.sig"))
+	if err != nil {
+		t.Fatalf("failed to read signature: %v", err)
+	}
+	return config, signature
+}
+
+func newTestFetcher(t *testing.T) *Fetcher {
+	t.Helper()
+
+	dir := t.TempDir()
+	config, signature := repoDeliveryConfig(t)
+	return &Fetcher{
+		client:  &http.Client{Transport: deliveryTransport{config, signature}},
+		paths:   &Paths{DataDir: dir},
+		secrets: secrets.NewFileStore(dir),
+	}
+}
+
+// withTimeout fails the test if fn does not return in time, e.g. because it
+// deadlocked on the app config lock.
+func withTimeout(t *testing.T, name string, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return", name)
+	}
+}
+
+func TestAppConfigWithReachableDeliveryConfig(t *testing.T) {
+	f := newTestFetcher(t)
+
+	withTimeout(t, "LoadAppConfig", func() {
+		_, err := f.LoadAppConfig()
+		if err != nil {
+			t.Errorf("LoadAppConfig failed: %v", err)
+		}
+	})
+	withTimeout(t, "EnsureAppConfigExists", func() {
+		err := f.EnsureAppConfigExists()
+		if err != nil {
+			t.Errorf("EnsureAppConfigExists failed: %v", err)
+		}
+	})
+	withTimeout(t, "UpdateAppConfig", func() {
+		err := f.SetChannel("beta")
+		if err != nil {
+			t.Errorf("SetChannel failed: %v", err)
+		}
+	})
+	withTimeout(t, "LoadAppConfig", func() {
+		appConfig, err := f.LoadAppConfig()
+		if err != nil {
+			t.Errorf("LoadAppConfig failed: %v", err)
+			return
+		}
+		if appConfig.Channel != "beta" {
+			t.Errorf("got channel %q, want beta", appConfig.Channel)
+		}
+	})
+}
+
+func TestStoreMinClientVersion(t *testing.T) {
+	f := newTestFetcher(t)
+
+	withTimeout(t, "StoreMinClientVersion", func() {
+		deliveryConfig, err := f.FetchDeliveryConfig()
+		if err != nil {
+			t.Errorf("FetchDeliveryConfig failed: %v", err)
+			return
+		}
+
+		err = f.StoreMinClientVersion("2.0.0")
+		if err != nil {
+			t.Errorf("StoreMinClientVersion failed: %v", err)
+			return
+		}
+		if got := f.MinClientVersion(); got != "2.0.0" {
+			t.Errorf("got minimum %q, want 2.0.0", got)
+		}
+
+		err = f.StoreMinClientVersion(deliveryConfig.MinClientVersion)
+		if err != nil {
+			t.Errorf("StoreMinClientVersion failed: %v", err)
+			return
+		}
+		if got := f.MinClientVersion(); got != deliveryConfig.MinClientVersion {
+			t.Errorf("got minimum %q, want %q", got, deliveryConfig.MinClientVersion)
+		}
+	})
+}
+
+func TestMigrateAppConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		stored     string
+		wantBackup bool
+	}{
+		{
+			name:       "v0",
+			stored:     `{"subscription_url": "https://example.com/sub?token=secret", "current_sing_box_version": "1.11.15", "future_field": {"kept": true}}`,
+			wantBackup: true,
+		},
+		{
+			name:   "newer schema",
+			stored: `{"schema_version": 99, "current_sing_box_version": "1.11.15", "future_field": {"kept": true}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFetcher(t)
+			path := f.paths.AppConfig()
+			err := os.WriteFile(path, []byte(tt.stored), 0600)
+			if err != nil {
+				t.Fatalf("failed to write app config: %v", err)
+			}
+
+			appConfig, err := f.LoadAppConfig()
+			if err != nil {
+				t.Fatalf("LoadAppConfig failed: %v", err)
+			}
+			if appConfig.CurrentSingBoxVersion != "1.11.15" {
+				t.Errorf("got sing-box version %q, want 1.11.15", appConfig.CurrentSingBoxVersion)
+			}
+
+			// A save by this build must keep what it does not understand
+			err = f.SetChannel("beta")
+			if err != nil {
+				t.Fatalf("SetChannel failed: %v", err)
+			}
+
+			raw, err := f.readRawAppConfig()
+			if err != nil {
+				t.Fatalf("failed to read app config: %v", err)
+			}
+			var kept bytes.Buffer
+			err = json.Compact(&kept, raw["future_field"])
+			if err != nil || kept.String() != `{"kept":true}` {
+				t.Errorf("unknown field not kept: %s", raw["future_field"])
+			}
+			if _, ok := raw["subscription_url"]; ok {
+				t.Error("subscription URL left in the app config")
+			}
+
+			backup, err := os.ReadFile(path + ".v0.bak")
+			if !tt.wantBackup {
+				if err == nil {
+					t.Error("backed up an app config that needed no migration")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("no backup of the v0 app config: %v", err)
+			}
+			if strings.Contains(string(backup), "secret") {
+				t.Errorf("backup contains the subscription URL: %s", backup)
+			}
+			if !strings.Contains(string(backup), "future_field") || !strings.Contains(string(backup), "1.11.15") {
+				t.Errorf("backup lost fields: %s", backup)
+			}
+
+			if string(raw["schema_version"]) != fmt.Sprint(AppConfigSchemaVersion) {
+				t.Errorf("got schema_version %s, want %d", raw["schema_version"], AppConfigSchemaVersion)
+			}
+			if appConfig.SubscriptionURL != "https://example.com/sub?token=secret" {
+				t.Errorf("got subscription URL %q after migration", appConfig.SubscriptionURL)
+			}
+			stored, err := f.secrets.Get(subscriptionURLSecret)
+			if err != nil || stored != appConfig.SubscriptionURL {
+				t.Errorf("secret store holds %q, %v", stored, err)
+			}
+		})
+	}
+}
+
+func TestWritePrivateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app_config.json")
+
+	for _, content := range []string{"first", "second"} {
+		err := writePrivateFile(path, []byte(content))
+		if err != nil {
+			t.Fatalf("writePrivateFile failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Fatalf("got %q, %v, want %q", data, err, content)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("got mode %v, want 0600", info.Mode().Perm())
+		}
+	}
+}
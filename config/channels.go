@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+const DefaultChannelName = "stable"
+
+type ReleaseChannel struct {
+	Name               string `json:"-"`
+	SingBoxVersion     string `json:"sing_box_version"`
+	SingBoxZipURL      string `json:"sing_box_zip_url"`
+	SingBoxZipSHA256   string `json:"sing_box_zip_sha256,omitempty"`
+	InArchiveExecPath  string `json:"in_archive_exec_path"`
+	SingBoxLicenseFile string `json:"sing_box_license_file,omitempty"`
+}
+
+// ChannelNames lists the channels offered by the delivery config in stable order.
+func (d *DeliveryConfig) ChannelNames() []string {
+	if len(d.Channels) == 0 {
+		return []string{DefaultChannelName}
+	}
+
+	names := make([]string, 0, len(d.Channels))
+	for name := range d.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Channel returns the named channel, falling back to the delivery config's
+// default channel when name is empty. Delivery configs without channels
+// expose their top-level sing-box fields as the "stable" channel.
+func (d *DeliveryConfig) Channel(name string) (*ReleaseChannel, error) {
+	if len(d.Channels) == 0 {
+		if name != "" && name != DefaultChannelName {
+			return nil, fmt.Errorf("channel %q is not offered by the delivery config", name)
+		}
+		return &ReleaseChannel{
+			Name:               DefaultChannelName,
+			SingBoxVersion:     d.SingBoxVersion,
+			SingBoxZipURL:      d.SingBoxZipURL,
+			InArchiveExecPath:  d.InArchiveExecPath,
+			SingBoxLicenseFile: d.SingBoxLicenseFile,
+		}, nil
+	}
+
+	if name == "" {
+		name = d.DefaultChannel
+	}
+	if name == "" {
+		name = DefaultChannelName
+	}
+
+	channel, ok := d.Channels[name]
+	if !ok {
+		return nil, fmt.Errorf("channel %q is not offered by the delivery config", name)
+	}
+
+	channel.Name = name
+	if channel.SingBoxLicenseFile == "" {
+		channel.SingBoxLicenseFile = d.SingBoxLicenseFile
+	}
+	return &channel, nil
+}
@@ -1,12 +1,21 @@
 package config
 
 const (
-	DeliveryConfigURL = "https://raw.githubusercontent.com/pekashy/go-sing/a311c5534eeda63ab8c7c82ecbec5861ff1e2538/delivery/delivery_config.json"
+	// DeliveryConfigURL follows the main branch, which carries the signed
+	// config; the signature rather than a pinned commit guards its contents.
+	DeliveryConfigURL = "https://raw.githubusercontent.com/pekashy/go-sing/main/delivery/delivery_config.json"
+	// DeliveryConfigSignatureURL holds the detached ed25519 signature of the delivery config.
+	DeliveryConfigSignatureURL = DeliveryConfigURL + ".sig"
+	// DeliveryPublicKey verifies delivery config signatures (base64, raw ed25519 key).
+	DeliveryPublicKey = "Ge/i47p60sY2cfUdR189Rujsa244LHh/6NEon/qnxJ8="
 
 	GoSingDataDir     = "go-sing-data"
-	SingBoxExeName    = "sing-box.exe"
 	appConfigFile     = "app_config.json"
 	SingBoxConfigFile = "config.json"
 	SingBoxLogFile    = "sing-box.log"
+	AppLogFile        = "go-sing.log"
 	SingBoxLogDir     = "logs"
+
+	// SingBoxRunConfigFile is the config with go-sing's overrides applied.
+	SingBoxRunConfigFile = "config.run.json"
 )
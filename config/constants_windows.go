@@ -0,0 +1,3 @@
+package config
+
+const SingBoxExeName = "sing-box.exe"
@@ -3,38 +3,80 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"go-sing/internal/secrets"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sync"
 	"time"
 )
 
-type AppConfig struct {
-	SubscriptionURL       string `json:"subscription_url"`
-	CurrentSingBoxVersion string `json:"current_sing_box_version"`
+type DeliveryConfig struct {
+	DefaultSubscriptionURL string                    `json:"default_subscription_url"`
+	SingBoxLicenseFile     string                    `json:"sing_box_license_file"`
+	SingBoxZipURL          string                    `json:"sing_box_zip_url"`
+	SingBoxVersion         string                    `json:"sing_box_version"`
+	InArchiveExecPath      string                    `json:"in_archive_exec_path"`
+	DefaultChannel         string                    `json:"default_channel,omitempty"`
+	Channels               map[string]ReleaseChannel `json:"channels,omitempty"`
+	Client                 *ClientRelease            `json:"client,omitempty"`
+	MinClientVersion       string                    `json:"min_client_version,omitempty"`
+	Announcements          []Announcement            `json:"announcements,omitempty"`
 }
 
-type DeliveryConfig struct {
-	DefaultSubscriptionURL string `json:"default_subscription_url"`
-	SingBoxLicenseFile     string `json:"sing_box_license_file"`
-	SingBoxZipURL          string `json:"sing_box_zip_url"`
-	SingBoxVersion         string `json:"sing_box_version"`
-	InArchiveExecPath      string `json:"in_archive_exec_path"`
+// ClientRelease describes the latest go-sing client build offered to users.
+// Assets are keyed by "GOOS/GOARCH"; URL and SHA256 are the windows/amd64
+// build, as offered before other platforms were supported.
+type ClientRelease struct {
+	Version      string                 `json:"version"`
+	URL          string                 `json:"url,omitempty"`
+	SHA256       string                 `json:"sha256,omitempty"`
+	Assets       map[string]ClientAsset `json:"assets,omitempty"`
+	ReleaseNotes string                 `json:"release_notes,omitempty"`
+}
+
+type ClientAsset struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
 }
 
+// Asset returns the build of the release for goos and goarch, or nil if the
+// release has none.
+func (r *ClientRelease) Asset(goos, goarch string) *ClientAsset {
+	if asset, ok := r.Assets[goos+"/"+goarch]; ok && asset.URL != "" {
+		return &asset
+	}
+	if goos == "windows" && goarch == "amd64" && r.URL != "" {
+		return &ClientAsset{URL: r.URL, SHA256: r.SHA256}
+	}
+	return nil
+}
+
+const subscriptionURLSecret = "subscription_url"
+
 type Fetcher struct {
-	client *http.Client
+	client         *http.Client
+	paths          *Paths
+	secrets        secrets.Store
+	appConfigMutex sync.Mutex
+
+	subscriptionInfoMutex sync.RWMutex
+	subscriptionInfo      *SubscriptionInfo
 }
 
-func NewFetcher() *Fetcher {
+func NewFetcher(paths *Paths) *Fetcher {
 	return &Fetcher{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		paths:   paths,
+		secrets: secrets.NewCachedStore(secrets.NewStore(paths.DataDir)),
 	}
 }
 
+func (f *Fetcher) Paths() *Paths {
+	return f.paths
+}
+
 func (f *Fetcher) FetchConfig(url string) (string, error) {
 	resp, err := f.client.Get(url)
 	if err != nil {
@@ -51,6 +93,12 @@ func (f *Fetcher) FetchConfig(url string) (string, error) {
 		return "", err
 	}
 
+	if info, ok := ParseSubscriptionInfo(resp.Header.Get("Subscription-Userinfo")); ok {
+		f.subscriptionInfoMutex.Lock()
+		f.subscriptionInfo = info
+		f.subscriptionInfoMutex.Unlock()
+	}
+
 	config := string(body)
 
 	err = f.SaveConfig(config)
@@ -61,21 +109,18 @@ func (f *Fetcher) FetchConfig(url string) (string, error) {
 	return config, nil
 }
 
-func (f *Fetcher) SaveConfig(config string) error {
-	execPath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
+// SubscriptionInfo returns the quota reported with the last fetched config,
+// or nil if the provider sent none.
+func (f *Fetcher) SubscriptionInfo() *SubscriptionInfo {
+	f.subscriptionInfoMutex.RLock()
+	defer f.subscriptionInfoMutex.RUnlock()
+	return f.subscriptionInfo
+}
 
-	appDir := filepath.Dir(execPath)
-	dataDir := filepath.Join(appDir, GoSingDataDir)
-	err = os.MkdirAll(dataDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
-	configPath := filepath.Join(dataDir, SingBoxConfigFile)
+func (f *Fetcher) SaveConfig(config string) error {
+	configPath := f.paths.SingBoxConfig()
 
-	err = os.WriteFile(configPath, []byte(config), 0644)
+	err := writePrivateFile(configPath, []byte(config))
 	if err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
@@ -84,157 +129,129 @@ func (f *Fetcher) SaveConfig(config string) error {
 }
 
 func (f *Fetcher) GetConfigPath() (string, error) {
-	execPath, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	appDir := filepath.Dir(execPath)
-	dataDir := filepath.Join(appDir, GoSingDataDir)
-	return filepath.Join(dataDir, SingBoxConfigFile), nil
+	return f.paths.SingBoxConfig(), nil
 }
 
-func (f *Fetcher) LoadAppConfig() (*AppConfig, error) {
-	execPath, err := os.Executable()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get executable path: %w", err)
+func (f *Fetcher) getDefaultSubscriptionURL() string {
+	// Try to fetch from delivery config first
+	deliveryConfig, err := f.FetchDeliveryConfig()
+	if err == nil && deliveryConfig.DefaultSubscriptionURL != "" {
+		return deliveryConfig.DefaultSubscriptionURL
 	}
 
-	appDir := filepath.Dir(execPath)
-	dataDir := filepath.Join(appDir, GoSingDataDir)
-	err = os.MkdirAll(dataDir, 0755)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
-	appConfigPath := filepath.Join(dataDir, appConfigFile)
+	// Fallback to hardcoded constant if delivery config fails
+	return ""
+}
 
-	data, err := os.ReadFile(appConfigPath)
+func (f *Fetcher) fetchBytes(url string) ([]byte, error) {
+	resp, err := f.client.Get(url)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Try to get default URL from delivery config
-			defaultURL := f.getDefaultSubscriptionURL()
-			return &AppConfig{
-				SubscriptionURL: defaultURL,
-			}, nil
-		}
-		return nil, fmt.Errorf("failed to read app config: %w", err)
+		return nil, fmt.Errorf("failed to fetch from %s: %w", url, err)
 	}
+	defer resp.Body.Close()
 
-	var appConfig AppConfig
-	err = json.Unmarshal(data, &appConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse app config: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	if appConfig.SubscriptionURL == "" {
-		appConfig.SubscriptionURL = f.getDefaultSubscriptionURL()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return &appConfig, nil
+	return body, nil
 }
 
-func (f *Fetcher) SaveAppConfig(appConfig *AppConfig) error {
-	execPath, err := os.Executable()
+func (f *Fetcher) FetchDeliveryConfig() (*DeliveryConfig, error) {
+	body, err := f.fetchBytes(DeliveryConfigURL)
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return nil, err
 	}
 
-	appDir := filepath.Dir(execPath)
-	dataDir := filepath.Join(appDir, GoSingDataDir)
-	err = os.MkdirAll(dataDir, 0755)
+	signature, err := f.fetchBytes(DeliveryConfigSignatureURL)
 	if err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
+		return nil, fmt.Errorf("refusing unsigned delivery config: %w", err)
 	}
-	appConfigPath := filepath.Join(dataDir, appConfigFile)
 
-	data, err := json.MarshalIndent(appConfig, "", "  ")
+	err = VerifyDeliveryConfig(body, signature)
 	if err != nil {
-		return fmt.Errorf("failed to marshal app config: %w", err)
+		return nil, fmt.Errorf("refusing delivery config: %w", err)
 	}
 
-	err = os.WriteFile(appConfigPath, data, 0644)
+	var deliveryConfig DeliveryConfig
+	err = json.Unmarshal(body, &deliveryConfig)
 	if err != nil {
-		return fmt.Errorf("failed to save app config: %w", err)
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	return nil
+	return &deliveryConfig, nil
 }
 
-func (f *Fetcher) EnsureAppConfigExists() error {
-	execPath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	appDir := filepath.Dir(execPath)
-	dataDir := filepath.Join(appDir, GoSingDataDir)
-	err = os.MkdirAll(dataDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
-	appConfigPath := filepath.Join(dataDir, appConfigFile)
-
-	_, err = os.ReadFile(appConfigPath)
-	if err != nil {
-		defaultURL := f.getDefaultSubscriptionURL()
-		if defaultURL != "" {
-			appConfig := &AppConfig{
-				SubscriptionURL: defaultURL,
-			}
-			return f.SaveAppConfig(appConfig)
-		}
+// StoreMinClientVersion remembers the minimum client version of a verified
+// delivery config, so it is still enforced while the config cannot be fetched.
+// It takes the app config lock, so callers of FetchDeliveryConfig persist it
+// themselves rather than having the fetch do it.
+func (f *Fetcher) StoreMinClientVersion(version string) error {
+	if f.MinClientVersion() == version {
 		return nil
 	}
 
-	return nil
-}
-
-func (f *Fetcher) getDefaultSubscriptionURL() string {
-	// Try to fetch from delivery config first
-	deliveryConfig, err := f.FetchDeliveryConfig()
-	if err == nil && deliveryConfig.DefaultSubscriptionURL != "" {
-		return deliveryConfig.DefaultSubscriptionURL
-	}
-
-	// Fallback to hardcoded constant if delivery config fails
-	return ""
+	return f.UpdateAppConfig(func(appConfig *AppConfig) error {
+		appConfig.MinClientVersion = version
+		return nil
+	})
 }
 
-func (f *Fetcher) fetchJSON(url string, target interface{}) error {
-	resp, err := f.client.Get(url)
+// MinClientVersion returns the minimum client version of the last delivery
+// config that passed verification. It only reads the local app config.
+func (f *Fetcher) MinClientVersion() string {
+	appConfig, err := f.loadStoredAppConfig()
 	if err != nil {
-		return fmt.Errorf("failed to fetch from %s: %w", url, err)
+		return ""
 	}
-	defer resp.Body.Close()
+	return appConfig.MinClientVersion
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
+func (f *Fetcher) SetChannel(channel string) error {
+	return f.UpdateAppConfig(func(appConfig *AppConfig) error {
+		appConfig.Channel = channel
+		return nil
+	})
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
+func (f *Fetcher) SkipClientVersion(version string) error {
+	return f.UpdateAppConfig(func(appConfig *AppConfig) error {
+		appConfig.SkippedClientVersion = version
+		return nil
+	})
+}
 
-	err = json.Unmarshal(body, target)
-	if err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
-	}
+func (f *Fetcher) MarkAnnouncementSeen(id string) error {
+	return f.UpdateAppConfig(func(appConfig *AppConfig) error {
+		for _, seen := range appConfig.SeenAnnouncements {
+			if seen == id {
+				return nil
+			}
+		}
 
-	return nil
+		appConfig.SeenAnnouncements = append(appConfig.SeenAnnouncements, id)
+		return nil
+	})
 }
 
-func (f *Fetcher) FetchDeliveryConfig() (*DeliveryConfig, error) {
-	var deliveryConfig DeliveryConfig
-	err := f.fetchJSON(DeliveryConfigURL, &deliveryConfig)
+// SelectedChannel resolves the release channel picked in the app config
+// against the channels offered by the delivery config.
+func (f *Fetcher) SelectedChannel(deliveryConfig *DeliveryConfig) (*ReleaseChannel, error) {
+	appConfig, err := f.loadStoredAppConfig()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to load app config: %w", err)
 	}
-	return &deliveryConfig, nil
+
+	return deliveryConfig.Channel(appConfig.Channel)
 }
 
-func (f *Fetcher) CheckSingBoxVersionMismatch(deliveryConfig *DeliveryConfig) (bool, error) {
-	appConfig, err := f.LoadAppConfig()
+func (f *Fetcher) CheckSingBoxVersionMismatch(channel *ReleaseChannel) (bool, error) {
+	appConfig, err := f.loadStoredAppConfig()
 	if err != nil {
 		return true, fmt.Errorf("failed to load app config: %w", err)
 	}
@@ -244,16 +261,13 @@ func (f *Fetcher) CheckSingBoxVersionMismatch(deliveryConfig *DeliveryConfig) (b
 		return true, nil
 	}
 
-	// Compare stored version with delivery config version
-	return appConfig.CurrentSingBoxVersion != deliveryConfig.SingBoxVersion, nil
+	// Compare stored version with the selected channel version
+	return appConfig.CurrentSingBoxVersion != channel.SingBoxVersion, nil
 }
 
 func (f *Fetcher) UpdateSingBoxVersion(version string) error {
-	appConfig, err := f.LoadAppConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load app config: %w", err)
-	}
-
-	appConfig.CurrentSingBoxVersion = version
-	return f.SaveAppConfig(appConfig)
+	return f.UpdateAppConfig(func(appConfig *AppConfig) error {
+		appConfig.CurrentSingBoxVersion = version
+		return nil
+	})
 }
@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestClientReleaseAsset(t *testing.T) {
+	legacy := &ClientRelease{Version: "1.2.0", URL: "https://example.com/go-sing.exe", SHA256: "aa"}
+	multi := &ClientRelease{
+		Version: "1.2.0",
+		URL:     "https://example.com/go-sing.exe",
+		SHA256:  "aa",
+		Assets: map[string]ClientAsset{
+			"windows/amd64": {URL: "https://example.com/windows/go-sing.exe", SHA256: "bb"},
+			"linux/amd64":   {URL: "https://example.com/linux/go-sing", SHA256: "cc"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		release  *ClientRelease
+		platform [2]string
+		wantURL  string
+	}{
+		{"legacy windows", legacy, [2]string{"windows", "amd64"}, "https://example.com/go-sing.exe"},
+		{"legacy linux", legacy, [2]string{"linux", "amd64"}, ""},
+		{"legacy darwin", legacy, [2]string{"darwin", "arm64"}, ""},
+		{"legacy windows arm", legacy, [2]string{"windows", "arm64"}, ""},
+		{"asset windows", multi, [2]string{"windows", "amd64"}, "https://example.com/windows/go-sing.exe"},
+		{"asset linux", multi, [2]string{"linux", "amd64"}, "https://example.com/linux/go-sing"},
+		{"missing asset", multi, [2]string{"darwin", "arm64"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := tt.release.Asset(tt.platform[0], tt.platform[1])
+			got := ""
+			if asset != nil {
+				got = asset.URL
+			}
+			if got != tt.wantURL {
+				t.Fatalf("got %q, want %q", got, tt.wantURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ImportLink is a subscription offered through a sing-box:// or clash://
+// deep link.
+type ImportLink struct {
+	Name string
+	URL  string
+}
+
+var importLinkPrefixes = []string{"sing-box://", "clash://"}
+
+// IsImportLink reports whether arg looks like a deep link go-sing handles.
+func IsImportLink(arg string) bool {
+	for _, prefix := range importLinkPrefixes {
+		if strings.HasPrefix(strings.ToLower(arg), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseImportLink parses sing-box://import-remote-profile?url=...#name and
+// clash://install-config?url=...&name=... links.
+func ParseImportLink(link string) (*ImportLink, error) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse link: %w", err)
+	}
+
+	action := parsed.Host + strings.TrimSuffix(parsed.Path, "/")
+	query := parsed.Query()
+
+	var importLink ImportLink
+	switch {
+	case strings.EqualFold(parsed.Scheme, "sing-box") && action == "import-remote-profile":
+		importLink.Name = parsed.Fragment
+	case strings.EqualFold(parsed.Scheme, "clash") && action == "install-config":
+		importLink.Name = query.Get("name")
+	default:
+		return nil, fmt.Errorf("unsupported link %s://%s", parsed.Scheme, action)
+	}
+	importLink.URL = query.Get("url")
+
+	subscription, err := url.Parse(importLink.URL)
+	if err != nil || (subscription.Scheme != "http" && subscription.Scheme != "https") || subscription.Host == "" {
+		return nil, fmt.Errorf("link does not contain an http(s) subscription URL")
+	}
+
+	if importLink.Name == "" {
+		importLink.Name = subscription.Hostname()
+	}
+	return &importLink, nil
+}
+
+// ParseSharedSubscription accepts the forms a subscription is usually shared
+// in: a sing-box:// or clash:// import link, or a plain http(s) URL.
+func ParseSharedSubscription(text string) (*ImportLink, error) {
+	text = strings.TrimSpace(text)
+	if IsImportLink(text) {
+		return ParseImportLink(text)
+	}
+
+	subscription, err := url.Parse(text)
+	if err != nil || (subscription.Scheme != "http" && subscription.Scheme != "https") || subscription.Host == "" {
+		return nil, fmt.Errorf("not a subscription URL or import link")
+	}
+	return &ImportLink{Name: subscription.Hostname(), URL: text}, nil
+}
+
+// SingBoxLink formats the subscription as a sing-box import link, which the
+// sing-box apps for Android and iOS can scan.
+func (l *ImportLink) SingBoxLink() string {
+	return "sing-box://import-remote-profile?url=" + url.QueryEscape(l.URL) + "#" + url.PathEscape(l.Name)
+}
+
+// SubscriptionName names a subscription URL for display: its #fragment if
+// it has one, as import links do, otherwise its host.
+func SubscriptionName(subscriptionURL string) string {
+	subscription, err := url.Parse(strings.TrimSpace(subscriptionURL))
+	if err != nil {
+		return ""
+	}
+	if subscription.Fragment != "" {
+		return subscription.Fragment
+	}
+	return subscription.Hostname()
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"go-sing/internal/logrotate"
+	"time"
+)
+
+// AppLogPolicy keeps go-sing's own log to a few small daily files.
+var AppLogPolicy = logrotate.Policy{
+	MaxSize:      5 << 20,
+	MaxAge:       24 * time.Hour,
+	MaxArchives:  10,
+	MaxTotalSize: 20 << 20,
+}
+
+// SingBoxLogPolicy bounds the sing-box logs archived on every launch.
+var SingBoxLogPolicy = logrotate.Policy{
+	MaxArchives:  10,
+	MaxTotalSize: 100 << 20,
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	appName = "go-sing"
+	// PortableMarkerFile next to the executable keeps all data in GoSingDataDir beside it.
+	PortableMarkerFile = "portable"
+	// PortableEnv set to a non-empty value forces portable mode.
+	PortableEnv = "GO_SING_PORTABLE"
+
+	migratedMarkerFile = ".migrated"
+)
+
+// Paths resolves where go-sing keeps its data. By default that is a per-user
+// directory; portable installs keep it next to the executable.
+type Paths struct {
+	DataDir  string
+	Portable bool
+}
+
+func ResolvePaths() (*Paths, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executable path: %w", err)
+	}
+	legacyDir := filepath.Join(filepath.Dir(execPath), GoSingDataDir)
+
+	if isPortable(filepath.Dir(execPath)) {
+		paths := &Paths{DataDir: legacyDir, Portable: true}
+		return paths, paths.ensureDataDir()
+	}
+
+	userDir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := &Paths{DataDir: userDir}
+	err = paths.ensureDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	err = paths.migrateFrom(legacyDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate %s: %w", legacyDir, err)
+	}
+
+	return paths, nil
+}
+
+func isPortable(execDir string) bool {
+	if os.Getenv(PortableEnv) != "" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(execDir, PortableMarkerFile))
+	return err == nil
+}
+
+// userDataDir follows the platform convention: %APPDATA% on Windows,
+// Application Support on macOS and $XDG_DATA_HOME elsewhere.
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		dir := os.Getenv("APPDATA")
+		if dir == "" {
+			return "", fmt.Errorf("%%APPDATA%% is not set")
+		}
+		return filepath.Join(dir, appName), nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(home, "Library", "Application Support", appName), nil
+	default:
+		dir := os.Getenv("XDG_DATA_HOME")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to get home directory: %w", err)
+			}
+			dir = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(dir, appName), nil
+	}
+}
+
+func (p *Paths) ensureDataDir() error {
+	err := os.MkdirAll(p.DataDir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return nil
+}
+
+// migrateFrom copies the contents of the pre per-user data directory once.
+// The old directory is left in place as it may not be writable.
+func (p *Paths) migrateFrom(legacyDir string) error {
+	markerPath := filepath.Join(p.DataDir, migratedMarkerFile)
+	if _, err := os.Stat(markerPath); err == nil {
+		return nil
+	}
+
+	if info, err := os.Stat(legacyDir); err == nil && info.IsDir() {
+		err = copyDir(legacyDir, p.DataDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(markerPath, []byte(legacyDir+"\n"), 0600)
+}
+
+func copyDir(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+
+		// Never clobber data already in the new location
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (p *Paths) SingBoxExe() string {
+	return filepath.Join(p.DataDir, SingBoxExeName)
+}
+
+func (p *Paths) SingBoxConfig() string {
+	return filepath.Join(p.DataDir, SingBoxConfigFile)
+}
+
+func (p *Paths) SingBoxRunConfig() string {
+	return filepath.Join(p.DataDir, SingBoxRunConfigFile)
+}
+
+func (p *Paths) AppConfig() string {
+	return filepath.Join(p.DataDir, appConfigFile)
+}
+
+func (p *Paths) LogsDir() string {
+	return filepath.Join(p.DataDir, SingBoxLogDir)
+}
+
+func (p *Paths) SingBoxLog() string {
+	return filepath.Join(p.LogsDir(), SingBoxLogFile)
+}
+
+func (p *Paths) AppLog() string {
+	return filepath.Join(p.LogsDir(), AppLogFile)
+}
+
+// File returns the path of an arbitrary file in the data directory.
+func (p *Paths) File(name string) string {
+	return filepath.Join(p.DataDir, name)
+}
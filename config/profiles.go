@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-sing/internal/secrets"
+	"slices"
+	"strconv"
+)
+
+// profileURLSecretPrefix keys the URL of each profile in the secret store.
+const profileURLSecretPrefix = "profile_url:"
+
+// Profile is a saved subscription that can be switched to. The app config
+// only lists profile names; the URLs are kept in the secret store.
+type Profile struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Profiles returns the saved subscriptions and the name of the one in use.
+func (f *Fetcher) Profiles() ([]Profile, string, error) {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	appConfig, err := f.loadAppConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	profiles, err := f.loadProfiles(appConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	return profiles, currentProfile(appConfig, profiles), nil
+}
+
+// AddProfile saves url as a profile and makes it the one in use. A URL that
+// is already saved keeps its profile; otherwise the profile is called name,
+// or after the URL's host when name is empty. It returns the profile's name.
+func (f *Fetcher) AddProfile(name, url string) (string, error) {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	appConfig, err := f.loadAppConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load app config: %w", err)
+	}
+	profiles, err := f.loadProfiles(appConfig)
+	if err != nil {
+		return "", err
+	}
+
+	index := slices.IndexFunc(profiles, func(p Profile) bool { return p.URL == url })
+	if index < 0 {
+		if name == "" {
+			name = SubscriptionName(url)
+		}
+		name = uniqueProfileName(profiles, name)
+		profiles = append(profiles, Profile{Name: name, URL: url})
+		index = len(profiles) - 1
+	}
+
+	return profiles[index].Name, f.saveProfiles(appConfig, profiles, profiles[index])
+}
+
+// SwitchProfile makes the named profile the one in use and returns it. The
+// caller fetches its config.
+func (f *Fetcher) SwitchProfile(name string) (*Profile, error) {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	appConfig, err := f.loadAppConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load app config: %w", err)
+	}
+	profiles, err := f.loadProfiles(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(profiles, func(p Profile) bool { return p.Name == name })
+	if index < 0 {
+		return nil, fmt.Errorf("no profile called %q", name)
+	}
+
+	profile := profiles[index]
+	return &profile, f.saveProfiles(appConfig, profiles, profile)
+}
+
+// RemoveProfile forgets a profile other than the one in use.
+func (f *Fetcher) RemoveProfile(name string) error {
+	f.appConfigMutex.Lock()
+	defer f.appConfigMutex.Unlock()
+
+	appConfig, err := f.loadAppConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load app config: %w", err)
+	}
+	profiles, err := f.loadProfiles(appConfig)
+	if err != nil {
+		return err
+	}
+
+	current := currentProfile(appConfig, profiles)
+	if name == current {
+		return fmt.Errorf("profile %q is in use, switch to another one first", name)
+	}
+
+	index := slices.IndexFunc(profiles, func(p Profile) bool { return p.Name == name })
+	if index < 0 {
+		return fmt.Errorf("no profile called %q", name)
+	}
+	profiles = slices.Delete(profiles, index, index+1)
+
+	err = f.secrets.Delete(profileURLSecretPrefix + name)
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return fmt.Errorf("failed to delete profile %q: %w", name, err)
+	}
+
+	currentIndex := slices.IndexFunc(profiles, func(p Profile) bool { return p.Name == current })
+	if currentIndex < 0 {
+		// Profiles stay unchanged apart from the removed one
+		appConfig.Profiles = profileNames(profiles)
+		return f.saveAppConfig(appConfig)
+	}
+	return f.saveProfiles(appConfig, profiles, profiles[currentIndex])
+}
+
+// loadProfiles reads the URLs of the saved profiles. A subscription saved
+// before profiles existed is returned as the only profile.
+func (f *Fetcher) loadProfiles(appConfig *AppConfig) ([]Profile, error) {
+	var profiles []Profile
+	for _, name := range appConfig.Profiles {
+		url, err := f.secrets.Get(profileURLSecretPrefix + name)
+		if errors.Is(err, secrets.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile %q: %w", name, err)
+		}
+		profiles = append(profiles, Profile{Name: name, URL: url})
+	}
+
+	if len(profiles) == 0 && appConfig.SubscriptionURL != "" {
+		name := appConfig.Profile
+		if name == "" {
+			name = SubscriptionName(appConfig.SubscriptionURL)
+		}
+		profiles = append(profiles, Profile{Name: name, URL: appConfig.SubscriptionURL})
+	}
+	return profiles, nil
+}
+
+// saveProfiles stores profiles and makes current the one in use. Called with
+// the app config lock held.
+func (f *Fetcher) saveProfiles(appConfig *AppConfig, profiles []Profile, current Profile) error {
+	for _, profile := range profiles {
+		err := f.secrets.Set(profileURLSecretPrefix+profile.Name, profile.URL)
+		if err != nil {
+			return fmt.Errorf("failed to store profile %q: %w", profile.Name, err)
+		}
+	}
+
+	appConfig.Profiles = profileNames(profiles)
+	appConfig.Profile = current.Name
+	appConfig.SubscriptionURL = current.URL
+	return f.saveAppConfig(appConfig)
+}
+
+func currentProfile(appConfig *AppConfig, profiles []Profile) string {
+	for _, profile := range profiles {
+		if profile.URL == appConfig.SubscriptionURL && (appConfig.Profile == "" || profile.Name == appConfig.Profile) {
+			return profile.Name
+		}
+	}
+	return ""
+}
+
+func profileNames(profiles []Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// uniqueProfileName appends a number to name while another profile has it.
+func uniqueProfileName(profiles []Profile, name string) string {
+	if name == "" {
+		name = "Subscription"
+	}
+
+	taken := func(candidate string) bool {
+		return slices.ContainsFunc(profiles, func(p Profile) bool { return p.Name == candidate })
+	}
+	candidate := name
+	for i := 2; taken(candidate); i++ {
+		candidate = name + " " + strconv.Itoa(i)
+	}
+	return candidate
+}
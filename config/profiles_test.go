@@ -0,0 +1,91 @@
+package config
+
+import (
+	"go-sing/internal/secrets"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	f := &Fetcher{paths: &Paths{DataDir: dir}, secrets: secrets.NewFileStore(dir)}
+
+	// A subscription saved before profiles existed
+	err := f.SaveAppConfig(&AppConfig{SubscriptionURL: "https://old.example.com/sub?token=1"})
+	if err != nil {
+		t.Fatalf("SaveAppConfig failed: %v", err)
+	}
+	assertProfiles(t, f, []string{"old.example.com"}, "old.example.com")
+
+	name, err := f.AddProfile("Work", "https://work.example.com/sub")
+	if err != nil {
+		t.Fatalf("AddProfile failed: %v", err)
+	}
+	if name != "Work" {
+		t.Fatalf("got name %q, want Work", name)
+	}
+	assertProfiles(t, f, []string{"old.example.com", "Work"}, "Work")
+
+	// A saved URL keeps its profile, a taken name gets a number
+	name, _ = f.AddProfile("Other", "https://old.example.com/sub?token=1")
+	if name != "old.example.com" {
+		t.Fatalf("got name %q for a saved URL, want old.example.com", name)
+	}
+	name, _ = f.AddProfile("Work", "https://work.example.com/other")
+	if name != "Work 2" {
+		t.Fatalf("got name %q, want Work 2", name)
+	}
+
+	profile, err := f.SwitchProfile("Work")
+	if err != nil {
+		t.Fatalf("SwitchProfile failed: %v", err)
+	}
+	if profile.URL != "https://work.example.com/sub" {
+		t.Fatalf("got URL %q", profile.URL)
+	}
+	appConfig, err := f.LoadAppConfig()
+	if err != nil {
+		t.Fatalf("LoadAppConfig failed: %v", err)
+	}
+	if appConfig.SubscriptionURL != profile.URL {
+		t.Fatalf("subscription URL is %q, want %q", appConfig.SubscriptionURL, profile.URL)
+	}
+
+	if _, err := f.SwitchProfile("missing"); err == nil {
+		t.Fatal("switching to a missing profile succeeded")
+	}
+	if err := f.RemoveProfile("Work"); err == nil {
+		t.Fatal("removing the profile in use succeeded")
+	}
+	if err := f.RemoveProfile("Work 2"); err != nil {
+		t.Fatalf("RemoveProfile failed: %v", err)
+	}
+	assertProfiles(t, f, []string{"old.example.com", "Work"}, "Work")
+
+	// URLs stay out of the app config file
+	raw, err := f.readRawAppConfig()
+	if err != nil {
+		t.Fatalf("failed to read app config: %v", err)
+	}
+	for key, value := range raw {
+		if strings.Contains(string(value), "example.com/") {
+			t.Fatalf("app config field %s holds a URL: %s", key, value)
+		}
+	}
+}
+
+func assertProfiles(t *testing.T, f *Fetcher, wantNames []string, wantCurrent string) {
+	t.Helper()
+
+	profiles, current, err := f.Profiles()
+	if err != nil {
+		t.Fatalf("Profiles failed: %v", err)
+	}
+	if names := profileNames(profiles); !slices.Equal(names, wantNames) {
+		t.Fatalf("got profiles %v, want %v", names, wantNames)
+	}
+	if current != wantCurrent {
+		t.Fatalf("got current profile %q, want %q", current, wantCurrent)
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+const redacted = "<redacted>"
+
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// RedactURL keeps only the scheme and host of a URL, since subscription URLs
+// usually carry an access token in the user info, path or query.
+func RedactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return redacted
+	}
+
+	result := parsed.Scheme + "://" + parsed.Host
+	if parsed.User != nil || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" {
+		result += "/" + redacted
+	}
+	return result
+}
+
+// RedactSecrets redacts every URL found in text.
+func RedactSecrets(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, RedactURL)
+}
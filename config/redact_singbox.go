@@ -0,0 +1,244 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+
+// Credential fields of sing-box inbounds, outbounds and endpoints.
+var credentialKeys = map[string]bool{
+	"password":        true,
+	"uuid":            true,
+	"username":        true,
+	"auth":            true,
+	"auth_str":        true,
+	"private_key":     true,
+	"pre_shared_key":  true,
+	"peer_public_key": true,
+	"public_key":      true,
+	"short_id":        true,
+	"reserved":        true,
+	"psk":             true,
+	"token":           true,
+	"key":             true,
+	"key_path":        true,
+	"secret":          true,
+}
+
+// Fields naming the server a client connects to.
+var hostnameKeys = map[string]bool{
+	"server":      true,
+	"server_name": true,
+	"address":     true,
+	"host":        true,
+}
+
+// Redactor masks credentials in sing-box configs and, optionally, the server
+// hostnames. Hostnames are replaced by stable placeholders so the same server
+// reads the same in the redacted config and in logs passed through RedactText.
+type Redactor struct {
+	Hostnames bool
+	hosts     map[string]string
+}
+
+func NewRedactor(hideHostnames bool) *Redactor {
+	return &Redactor{
+		Hostnames: hideHostnames,
+		hosts:     make(map[string]string),
+	}
+}
+
+// RedactConfig returns the indented sing-box config with secrets masked.
+func (r *Redactor) RedactConfig(data []byte) (string, error) {
+	var root map[string]interface{}
+	err := json.Unmarshal(data, &root)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse sing-box config: %w", err)
+	}
+
+	for _, section := range []string{"inbounds", "outbounds", "endpoints"} {
+		items, _ := root[section].([]interface{})
+		for _, item := range items {
+			if object, ok := item.(map[string]interface{}); ok {
+				r.redactObject(object)
+			}
+		}
+	}
+
+	if dns, ok := root["dns"].(map[string]interface{}); ok {
+		r.redactDNS(dns)
+	}
+
+	if experimental, ok := root["experimental"].(map[string]interface{}); ok {
+		if clashAPI, ok := experimental["clash_api"].(map[string]interface{}); ok {
+			r.redactCredential(clashAPI, "secret")
+		}
+	}
+
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal redacted config: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// RedactText masks URLs, UUIDs and, when hiding hostnames, every server seen
+// by RedactConfig in free text such as logs.
+func (r *Redactor) RedactText(text string) string {
+	text = RedactSecrets(text)
+	text = uuidPattern.ReplaceAllString(text, redacted)
+
+	if !r.Hostnames {
+		return text
+	}
+
+	if len(r.hosts) == 0 {
+		return text
+	}
+
+	// Try longer hostnames first so a host is not clobbered by its parent domain
+	hosts := make([]string, 0, len(r.hosts))
+	for host := range r.hosts {
+		hosts = append(hosts, regexp.QuoteMeta(host))
+	}
+	sort.Slice(hosts, func(i, j int) bool { return len(hosts[i]) > len(hosts[j]) })
+	pattern := regexp.MustCompile(strings.Join(hosts, "|"))
+
+	var result strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		if !isWholeHost(text, start, end) {
+			continue
+		}
+		result.WriteString(text[last:start])
+		result.WriteString(r.hosts[text[start:end]])
+		last = end
+	}
+	result.WriteString(text[last:])
+	return result.String()
+}
+
+// isWholeHost reports whether text[start:end] is a host on its own or the
+// parent domain of one, rather than part of a longer name or address. A
+// sentence's final dot may follow it.
+func isWholeHost(text string, start, end int) bool {
+	if start > 0 && text[start-1] != '.' && isHostByte(text[start-1]) {
+		return false
+	}
+	if end == len(text) {
+		return true
+	}
+	if text[end] == '.' {
+		return end+1 == len(text) || !isHostByte(text[end+1])
+	}
+	return !isHostByte(text[end])
+}
+
+func isHostByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+}
+
+// redactObject walks an inbound, outbound or endpoint including its nested
+// tls, transport, users, peers and obfs objects.
+func (r *Redactor) redactObject(object map[string]interface{}) {
+	// Walk keys in order so placeholders are numbered the same on every run
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch typed := object[key].(type) {
+		case map[string]interface{}:
+			if key == "transport" {
+				r.redactCredential(typed, "path")
+				r.redactCredential(typed, "service_name")
+			}
+			if key == "headers" {
+				r.redactHostname(typed, "Host")
+			}
+			r.redactObject(typed)
+		case []interface{}:
+			for i, item := range typed {
+				switch element := item.(type) {
+				case map[string]interface{}:
+					r.redactObject(element)
+				case string:
+					if key == "host" && r.Hostnames {
+						typed[i] = r.placeholder(element)
+					}
+				}
+			}
+			if credentialKeys[key] {
+				object[key] = redacted
+			}
+		default:
+			if credentialKeys[key] {
+				r.redactCredential(object, key)
+			} else if hostnameKeys[key] {
+				r.redactHostname(object, key)
+			} else if key == "obfs" {
+				r.redactCredential(object, key)
+			}
+		}
+	}
+}
+
+func (r *Redactor) redactDNS(dns map[string]interface{}) {
+	servers, _ := dns["servers"].([]interface{})
+	for _, item := range servers {
+		server, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if address, ok := server["address"].(string); ok {
+			if strings.Contains(address, "://") {
+				server["address"] = RedactURL(address)
+			}
+		}
+		if r.Hostnames {
+			r.redactHostname(server, "address")
+			r.redactHostname(server, "server")
+		}
+	}
+}
+
+func (r *Redactor) redactCredential(object map[string]interface{}, key string) {
+	if value, ok := object[key]; ok && value != "" {
+		object[key] = redacted
+	}
+}
+
+func (r *Redactor) redactHostname(object map[string]interface{}, key string) {
+	if !r.Hostnames {
+		return
+	}
+
+	value, ok := object[key].(string)
+	if !ok || value == "" {
+		return
+	}
+	object[key] = r.placeholder(value)
+}
+
+func (r *Redactor) placeholder(host string) string {
+	if placeholder, ok := r.hosts[host]; ok {
+		return placeholder
+	}
+
+	placeholder := fmt.Sprintf("server-%d.invalid", len(r.hosts)+1)
+	r.hosts[host] = placeholder
+	return placeholder
+}
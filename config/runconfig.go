@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SingBoxLogLevels are the levels sing-box accepts, most verbose first.
+var SingBoxLogLevels = []string{"trace", "debug", "info", "warn", "error", "fatal", "panic"}
+
+// OverridesSingBoxLog reports whether the settings change the log block of
+// the sing-box config.
+func (s Settings) OverridesSingBoxLog() bool {
+	return s.SingBoxLogLevel != "" || s.SingBoxLogToFile
+}
+
+// PrepareRunConfig returns the config sing-box should be started with. When
+// the settings override logging, they are applied to a copy so the fetched
+// config stays as the provider sent it.
+func PrepareRunConfig(paths *Paths, settings Settings) (string, error) {
+	if !settings.OverridesSingBoxLog() {
+		os.Remove(paths.SingBoxRunConfig())
+		return paths.SingBoxConfig(), nil
+	}
+
+	data, err := os.ReadFile(paths.SingBoxConfig())
+	if err != nil {
+		return "", fmt.Errorf("failed to read config: %w", err)
+	}
+
+	data, err = ApplyLogSettings(data, settings, paths.SingBoxLog())
+	if err != nil {
+		return "", fmt.Errorf("failed to apply log settings: %w", err)
+	}
+
+	err = writePrivateFile(paths.SingBoxRunConfig(), data)
+	if err != nil {
+		return "", fmt.Errorf("failed to save config: %w", err)
+	}
+	return paths.SingBoxRunConfig(), nil
+}
+
+// ApplyLogSettings overrides the level of the log block in a sing-box config
+// and, with SingBoxLogToFile, sends its output to logPath. Either one turns
+// logging back on if the provider disabled it.
+func ApplyLogSettings(data []byte, settings Settings, logPath string) ([]byte, error) {
+	var root map[string]json.RawMessage
+	err := json.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	logBlock := map[string]interface{}{}
+	if raw, ok := root["log"]; ok {
+		err = json.Unmarshal(raw, &logBlock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse log block: %w", err)
+		}
+	}
+
+	delete(logBlock, "disabled")
+	if settings.SingBoxLogLevel != "" {
+		logBlock["level"] = settings.SingBoxLogLevel
+	}
+	if settings.SingBoxLogToFile {
+		logBlock["output"] = logPath
+	}
+
+	root["log"], err = json.Marshal(logBlock)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// Events that show a desktop notification unless turned off in Settings.
+const (
+	NotifyConnected     = "connected"
+	NotifyDisconnected  = "disconnected"
+	NotifyCrashed       = "crashed"
+	NotifyConfigChanged = "config_changed"
+	NotifyCoreUpdated   = "core_updated"
+	NotifyQuota         = "quota"
+)
+
+var NotificationEvents = []string{NotifyConnected, NotifyDisconnected, NotifyCrashed, NotifyConfigChanged, NotifyCoreUpdated, NotifyQuota}
+
+// Settings holds the user tunables. Zero values mean "use the default" so
+// configs written before a setting existed pick up its default.
+type Settings struct {
+	ConfigWatchIntervalSeconds int    `json:"config_watch_interval_seconds,omitempty"`
+	BinaryCheckIntervalSeconds int    `json:"binary_check_interval_seconds,omitempty"`
+	LogPollIntervalMillis      int    `json:"log_poll_interval_ms,omitempty"`
+	LogViewRefreshMillis       int    `json:"log_view_refresh_ms,omitempty"`
+	RestartPolicy              string `json:"restart_policy,omitempty"`
+	HotReload                  bool   `json:"hot_reload,omitempty"`
+	AutoConnect                bool   `json:"auto_connect,omitempty"`
+	StartMinimized             bool   `json:"start_minimized,omitempty"`
+	LaunchAtLogin              bool   `json:"launch_at_login,omitempty"`
+	HandleLinks                bool   `json:"handle_links,omitempty"`
+	APIEnabled                 bool   `json:"api_enabled,omitempty"`
+	APIPort                    int    `json:"api_port,omitempty"`
+	SingBoxLogLevel            string `json:"sing_box_log_level,omitempty"`
+	SingBoxLogToFile           bool   `json:"sing_box_log_to_file,omitempty"`
+	// Notifications turns individual events off; missing ones are on.
+	Notifications map[string]bool `json:"notifications,omitempty"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		ConfigWatchIntervalSeconds: 60,
+		BinaryCheckIntervalSeconds: 5,
+		LogPollIntervalMillis:      500,
+		LogViewRefreshMillis:       1000,
+		RestartPolicy:              RestartNever,
+		APIPort:                    29583,
+	}
+}
+
+// WithDefaults returns a copy with every unset field filled from DefaultSettings.
+func (s Settings) WithDefaults() Settings {
+	defaults := DefaultSettings()
+	if s.ConfigWatchIntervalSeconds == 0 {
+		s.ConfigWatchIntervalSeconds = defaults.ConfigWatchIntervalSeconds
+	}
+	if s.BinaryCheckIntervalSeconds == 0 {
+		s.BinaryCheckIntervalSeconds = defaults.BinaryCheckIntervalSeconds
+	}
+	if s.LogPollIntervalMillis == 0 {
+		s.LogPollIntervalMillis = defaults.LogPollIntervalMillis
+	}
+	if s.LogViewRefreshMillis == 0 {
+		s.LogViewRefreshMillis = defaults.LogViewRefreshMillis
+	}
+	if s.RestartPolicy == "" {
+		s.RestartPolicy = defaults.RestartPolicy
+	}
+	if s.APIPort == 0 {
+		s.APIPort = defaults.APIPort
+	}
+	return s
+}
+
+// Sanitized returns a copy with every out-of-range field reset to its
+// default, so a hand-edited app config never reaches the tickers.
+func (s Settings) Sanitized() Settings {
+	defaults := DefaultSettings()
+	if !validConfigWatchInterval(s.ConfigWatchIntervalSeconds) {
+		s.ConfigWatchIntervalSeconds = defaults.ConfigWatchIntervalSeconds
+	}
+	if !validBinaryCheckInterval(s.BinaryCheckIntervalSeconds) {
+		s.BinaryCheckIntervalSeconds = defaults.BinaryCheckIntervalSeconds
+	}
+	if !validLogPollInterval(s.LogPollIntervalMillis) {
+		s.LogPollIntervalMillis = defaults.LogPollIntervalMillis
+	}
+	if !validLogViewRefresh(s.LogViewRefreshMillis) {
+		s.LogViewRefreshMillis = defaults.LogViewRefreshMillis
+	}
+	if !validAPIPort(s.APIPort) {
+		s.APIPort = defaults.APIPort
+	}
+	if !validRestartPolicy(s.RestartPolicy) {
+		s.RestartPolicy = defaults.RestartPolicy
+	}
+	if !validSingBoxLogLevel(s.SingBoxLogLevel) {
+		s.SingBoxLogLevel = defaults.SingBoxLogLevel
+	}
+	return s
+}
+
+func (s Settings) Validate() error {
+	switch {
+	case !validConfigWatchInterval(s.ConfigWatchIntervalSeconds):
+		return fmt.Errorf("config watch interval must be between 10 seconds and 24 hours")
+	case !validBinaryCheckInterval(s.BinaryCheckIntervalSeconds):
+		return fmt.Errorf("sing-box check interval must be between 1 second and 1 hour")
+	case !validLogPollInterval(s.LogPollIntervalMillis):
+		return fmt.Errorf("log poll interval must be between 100 and 10000 ms")
+	case !validLogViewRefresh(s.LogViewRefreshMillis):
+		return fmt.Errorf("log view refresh interval must be between 100 and 10000 ms")
+	case !validAPIPort(s.APIPort):
+		return fmt.Errorf("API port must be between 1024 and 65535")
+	case !validRestartPolicy(s.RestartPolicy):
+		return fmt.Errorf("unknown restart policy %q", s.RestartPolicy)
+	case !validSingBoxLogLevel(s.SingBoxLogLevel):
+		return fmt.Errorf("unknown sing-box log level %q", s.SingBoxLogLevel)
+	}
+	return nil
+}
+
+func validConfigWatchInterval(seconds int) bool {
+	return seconds >= 10 && seconds <= 24*60*60
+}
+
+func validBinaryCheckInterval(seconds int) bool {
+	return seconds >= 1 && seconds <= 60*60
+}
+
+func validLogPollInterval(millis int) bool {
+	return millis >= 100 && millis <= 10000
+}
+
+func validLogViewRefresh(millis int) bool {
+	return millis >= 100 && millis <= 10000
+}
+
+func validAPIPort(port int) bool {
+	return port >= 1024 && port <= 65535
+}
+
+func validRestartPolicy(policy string) bool {
+	switch policy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+		return true
+	}
+	return false
+}
+
+func validSingBoxLogLevel(level string) bool {
+	return level == "" || slices.Contains(SingBoxLogLevels, level)
+}
+
+// Notifies reports whether a desktop notification is wanted for event.
+func (s Settings) Notifies(event string) bool {
+	enabled, ok := s.Notifications[event]
+	return !ok || enabled
+}
+
+func (s Settings) ConfigWatchInterval() time.Duration {
+	return time.Duration(s.ConfigWatchIntervalSeconds) * time.Second
+}
+
+func (s Settings) BinaryCheckInterval() time.Duration {
+	return time.Duration(s.BinaryCheckIntervalSeconds) * time.Second
+}
+
+func (s Settings) LogPollInterval() time.Duration {
+	return time.Duration(s.LogPollIntervalMillis) * time.Millisecond
+}
+
+func (s Settings) LogViewRefreshInterval() time.Duration {
+	return time.Duration(s.LogViewRefreshMillis) * time.Millisecond
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidSignature = errors.New("delivery config signature is invalid")
+
+func DecodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+func DecodePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("private key seed must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// SignDeliveryConfig returns the base64 encoded detached signature for data.
+func SignDeliveryConfig(data []byte, key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+}
+
+// VerifyDeliveryConfig checks a detached base64 signature over the raw
+// delivery config bytes against the public key compiled into the client.
+func VerifyDeliveryConfig(data []byte, signature []byte) error {
+	publicKey, err := DecodePublicKey(DeliveryPublicKey)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+
+	if !ed25519.Verify(publicKey, data, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestVerifyDeliveryConfig(t *testing.T) {
+	config, signature := repoDeliveryConfig(t)
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tampered := bytes.Replace(config, []byte(`"stable"`), []byte(`"stabl3"`), 1)
+	if bytes.Equal(tampered, config) {
+		t.Fatal("failed to tamper with the delivery config")
+	}
+
+	tests := []struct {
+		name      string
+		config    []byte
+		signature []byte
+		wantErr   error
+	}{
+		{"valid", config, signature, nil},
+		{"valid with trailing newline", config, append(bytes.TrimSpace(signature), '\n'), nil},
+		{"tampered", tampered, signature, ErrInvalidSignature},
+		{"trailing data", append(append([]byte{}, config...), ' '), signature, ErrInvalidSignature},
+		{"signed by another key", config, []byte(SignDeliveryConfig(config, otherKey)), ErrInvalidSignature},
+		{"empty signature", config, nil, ErrInvalidSignature},
+		{"truncated signature", config, signature[:20], ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyDeliveryConfig(tt.config, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := VerifyDeliveryConfig(config, []byte("not base64!")); err == nil {
+		t.Error("accepted a signature that is not base64")
+	}
+}
+
+func TestFetchDeliveryConfigRequiresSignature(t *testing.T) {
+	config, signature := repoDeliveryConfig(t)
+	tampered := bytes.Replace(config, []byte(`"stable"`), []byte(`"stabl3"`), 1)
+
+	tests := []struct {
+		name      string
+		transport deliveryTransport
+		wantErr   bool
+	}{
+		{"signed", deliveryTransport{config, signature}, false},
+		{"unsigned", deliveryTransport{config, nil}, true},
+		{"tampered", deliveryTransport{tampered, signature}, true},
+		{"missing", deliveryTransport{nil, nil}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{client: &http.Client{Transport: tt.transport}}
+			deliveryConfig, err := f.FetchDeliveryConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the delivery config to be refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchDeliveryConfig failed: %v", err)
+			}
+			if deliveryConfig.DefaultChannel != "stable" {
+				t.Fatalf("unexpected delivery config %+v", deliveryConfig)
+			}
+		})
+	}
+}
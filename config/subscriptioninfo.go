@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	quotaWarningFraction = 0.9
+	expiryWarningPeriod  = 3 * 24 * time.Hour
+)
+
+// SubscriptionInfo is the traffic quota and expiry a provider reports in the
+// subscription-userinfo response header.
+type SubscriptionInfo struct {
+	Upload   int64
+	Download int64
+	Total    int64
+	Expire   time.Time
+}
+
+// ParseSubscriptionInfo parses a header such as
+// "upload=1024; download=2048; total=10737418240; expire=1735689600".
+func ParseSubscriptionInfo(header string) (*SubscriptionInfo, bool) {
+	var info SubscriptionInfo
+	found := false
+
+	for _, field := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "upload":
+			info.Upload = number
+		case "download":
+			info.Download = number
+		case "total":
+			info.Total = number
+		case "expire":
+			if number > 0 {
+				info.Expire = time.Unix(number, 0)
+			}
+		default:
+			continue
+		}
+		found = true
+	}
+
+	if !found {
+		return nil, false
+	}
+	return &info, true
+}
+
+// Warning describes a quota that is nearly used up or a subscription about to
+// expire, or returns "" when neither applies.
+func (i *SubscriptionInfo) Warning(now time.Time) string {
+	used := i.Upload + i.Download
+	if i.Total > 0 && float64(used) >= quotaWarningFraction*float64(i.Total) {
+		return fmt.Sprintf("%s of %s traffic used", formatBytes(used), formatBytes(i.Total))
+	}
+	if !i.Expire.IsZero() && i.Expire.Sub(now) < expiryWarningPeriod {
+		if now.After(i.Expire) {
+			return "Subscription expired on " + i.Expire.Format("2006-01-02")
+		}
+		return "Subscription expires on " + i.Expire.Format("2006-01-02")
+	}
+	return ""
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
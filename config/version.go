@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ClientVersion is the go-sing release version. Release builds override it with
+// -ldflags "-X go-sing/config.ClientVersion=<version>".
+var ClientVersion = "1.1.0"
+
+// CompareVersions compares dotted versions such as "1.2.0" or "v1.12.0-rc.4".
+// It returns -1, 0 or 1. A pre-release sorts before its release, and build
+// metadata after "+" is ignored.
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	for i := 0; i < len(aCore) || i < len(bCore); i++ {
+		var x, y int
+		if i < len(aCore) {
+			x = aCore[i]
+		}
+		if i < len(bCore) {
+			y = bCore[i]
+		}
+		if x != y {
+			return compareInts(x, y)
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	default:
+		return comparePreReleases(aPre, bPre)
+	}
+}
+
+// comparePreReleases orders pre-release tags as semver does: identifier by
+// identifier, numerically when both are numbers, with numbers before words
+// and a shorter tag first when one is a prefix of the other.
+func comparePreReleases(a, b string) int {
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		x, xErr := strconv.Atoi(aIDs[i])
+		y, yErr := strconv.Atoi(bIDs[i])
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				return compareInts(x, y)
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aIDs[i], bIDs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInts(len(aIDs), len(bIDs))
+}
+
+func compareInts(x, y int) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func splitVersion(version string) ([]int, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+
+	pre := ""
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		pre = version[i+1:]
+		version = version[:i]
+	}
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	return parts, pre
+}
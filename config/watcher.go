@@ -12,25 +12,51 @@ type Logger interface {
 }
 
 type Watcher struct {
-	fetcher   *Fetcher
-	logger    Logger
-	url       string
-	ctx       context.Context
-	cancel    context.CancelFunc
-	mutex     sync.RWMutex
-	isRunning bool
+	fetcher         *Fetcher
+	logger          Logger
+	url             string
+	lastConfig      string
+	ctx             context.Context
+	cancel          context.CancelFunc
+	mutex           sync.RWMutex
+	isRunning       bool
+	interval        time.Duration
+	intervalChanged chan time.Duration
+	// OnConfigChanged is called when a periodic fetch returns a config that
+	// differs from the previous one.
+	OnConfigChanged func()
 }
 
 func NewConfigWatcher(fetcher *Fetcher, logger Logger) *Watcher {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Watcher{
-		fetcher: fetcher,
-		logger:  logger,
-		ctx:     ctx,
-		cancel:  cancel,
+		fetcher:         fetcher,
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+		interval:        DefaultSettings().ConfigWatchInterval(),
+		intervalChanged: make(chan time.Duration, 1),
 	}
 }
 
+// SetInterval changes how often the config is re-fetched, taking effect on
+// the running watch loop.
+func (w *Watcher) SetInterval(interval time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if interval == w.interval {
+		return
+	}
+	w.interval = interval
+
+	select {
+	case <-w.intervalChanged:
+	default:
+	}
+	w.intervalChanged <- interval
+}
+
 func (w *Watcher) Start() {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
@@ -61,20 +87,27 @@ func (w *Watcher) UpdateURL(url string) {
 	defer w.mutex.Unlock()
 
 	if url != "" && w.url != url {
-		w.logger.Log(fmt.Sprintf("Watching sing-box config: %s", url))
+		w.logger.Log(fmt.Sprintf("Watching sing-box config: %s", RedactURL(url)))
+		w.lastConfig = ""
 	}
 
 	w.url = url
 }
 
 func (w *Watcher) watchLoop() {
-	ticker := time.NewTicker(1 * time.Minute)
+	w.mutex.RLock()
+	interval := w.interval
+	w.mutex.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			w.checkAndUpdateConfig()
+		case interval := <-w.intervalChanged:
+			ticker.Reset(interval)
 		case <-w.ctx.Done():
 			return
 		}
@@ -90,9 +123,20 @@ func (w *Watcher) checkAndUpdateConfig() {
 		return
 	}
 
-	_, err := w.fetcher.FetchConfig(url)
+	config, err := w.fetcher.FetchConfig(url)
 	if err != nil {
 		w.logger.Log("Config watcher: Error fetching config - " + err.Error())
 		return
 	}
+
+	w.mutex.Lock()
+	changed := w.lastConfig != "" && w.lastConfig != config
+	w.lastConfig = config
+	callback := w.OnConfigChanged
+	w.mutex.Unlock()
+
+	if changed && callback != nil {
+		w.logger.Log("Config watcher: sing-box config changed")
+		callback()
+	}
 }
@@ -0,0 +1,8 @@
+// Package autostart registers go-sing to launch when the user logs in.
+package autostart
+
+const (
+	appName = "go-sing"
+	// MinimizedFlag is passed to go-sing when launched at login.
+	MinimizedFlag = "-minimized"
+)
@@ -0,0 +1,76 @@
+package autostart
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+)
+
+const launchAgentLabel = "com.github.pekashy.go-sing"
+
+func agentPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchAgentLabel+".plist"), nil
+}
+
+// Enable installs a LaunchAgent running execPath at login.
+func Enable(execPath string) error {
+	path, err := agentPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create LaunchAgents directory: %w", err)
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`, launchAgentLabel, html.EscapeString(execPath), MinimizedFlag)
+
+	err = os.WriteFile(path, []byte(plist), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write LaunchAgent: %w", err)
+	}
+	return nil
+}
+
+func Disable() error {
+	path, err := agentPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove LaunchAgent: %w", err)
+	}
+	return nil
+}
+
+func IsEnabled() bool {
+	path, err := agentPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,69 @@
+package autostart
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// entryPath follows the XDG autostart spec.
+func entryPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "autostart", appName+".desktop"), nil
+}
+
+// Enable writes an XDG autostart entry launching execPath.
+func Enable(execPath string) error {
+	path, err := entryPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create autostart directory: %w", err)
+	}
+
+	entry := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=Go Sing VPN Client
+Exec="%s" %s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, execPath, MinimizedFlag)
+
+	err = os.WriteFile(path, []byte(entry), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write autostart entry: %w", err)
+	}
+	return nil
+}
+
+func Disable() error {
+	path, err := entryPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove autostart entry: %w", err)
+	}
+	return nil
+}
+
+func IsEnabled() bool {
+	path, err := entryPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,19 @@
+//go:build !windows && !linux && !darwin
+
+package autostart
+
+import "errors"
+
+var errUnsupported = errors.New("launch at login is not supported on this platform")
+
+func Enable(execPath string) error {
+	return errUnsupported
+}
+
+func Disable() error {
+	return nil
+}
+
+func IsEnabled() bool {
+	return false
+}
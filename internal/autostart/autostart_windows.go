@@ -0,0 +1,45 @@
+package autostart
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+const runKey = `HKCU\Software\Microsoft\Windows\CurrentVersion\Run`
+
+func reg(args ...string) ([]byte, error) {
+	cmd := exec.Command("reg", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow: true,
+	}
+	return cmd.CombinedOutput()
+}
+
+// Enable adds execPath to the current user's Run key.
+func Enable(execPath string) error {
+	command := fmt.Sprintf(`"%s" %s`, execPath, MinimizedFlag)
+	output, err := reg("add", runKey, "/v", appName, "/t", "REG_SZ", "/d", command, "/f")
+	if err != nil {
+		return fmt.Errorf("failed to add Run key: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func Disable() error {
+	if !IsEnabled() {
+		return nil
+	}
+
+	output, err := reg("delete", runKey, "/v", appName, "/f")
+	if err != nil {
+		return fmt.Errorf("failed to remove Run key: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func IsEnabled() bool {
+	_, err := reg("query", runKey, "/v", appName)
+	return err == nil
+}
@@ -0,0 +1,7 @@
+// Package clipboard reads images from the system clipboard, which Fyne's
+// text-only clipboard cannot do.
+package clipboard
+
+import "errors"
+
+var ErrNoImage = errors.New("the clipboard does not contain an image")
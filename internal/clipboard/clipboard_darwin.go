@@ -0,0 +1,29 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"os/exec"
+	"strings"
+)
+
+// ReadImage fetches the clipboard as PNG through AppleScript, which prints
+// it as «data PNGf<hex>».
+func ReadImage() (image.Image, error) {
+	output, err := exec.Command("osascript", "-e", "the clipboard as «class PNGf»").Output()
+	if err != nil {
+		return nil, ErrNoImage
+	}
+
+	text := strings.TrimSpace(string(output))
+	text = strings.TrimPrefix(text, "«data PNGf")
+	text = strings.TrimSuffix(text, "»")
+
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, ErrNoImage
+	}
+	return png.Decode(bytes.NewReader(data))
+}
@@ -0,0 +1,34 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+)
+
+// ReadImage asks wl-paste (Wayland) or xclip (X11) for a PNG copy of the
+// clipboard.
+func ReadImage() (image.Image, error) {
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline", "--type", "image/png"})
+	}
+	commands = append(commands, []string{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"})
+
+	var lastErr error
+	for _, command := range commands {
+		output, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !bytes.HasPrefix(output, []byte("\x89PNG")) {
+			return nil, ErrNoImage
+		}
+		return png.Decode(bytes.NewReader(output))
+	}
+	return nil, fmt.Errorf("%w (install wl-clipboard or xclip to paste images): %v", ErrNoImage, lastErr)
+}
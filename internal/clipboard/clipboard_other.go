@@ -0,0 +1,12 @@
+//go:build !windows && !linux && !darwin
+
+package clipboard
+
+import (
+	"errors"
+	"image"
+)
+
+func ReadImage() (image.Image, error) {
+	return nil, errors.New("pasting images is not supported on this platform")
+}
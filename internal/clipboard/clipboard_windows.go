@@ -0,0 +1,107 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/image/bmp"
+)
+
+var (
+	user32                         = syscall.NewLazyDLL("user32.dll")
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procOpenClipboard              = user32.NewProc("OpenClipboard")
+	procCloseClipboard             = user32.NewProc("CloseClipboard")
+	procGetClipboardData           = user32.NewProc("GetClipboardData")
+	procRegisterClipboardFormatW   = user32.NewProc("RegisterClipboardFormatW")
+	procIsClipboardFormatAvailable = user32.NewProc("IsClipboardFormatAvailable")
+	procGlobalLock                 = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock               = kernel32.NewProc("GlobalUnlock")
+	procGlobalSize                 = kernel32.NewProc("GlobalSize")
+	procRtlMoveMemory              = kernel32.NewProc("RtlMoveMemory")
+)
+
+const CF_DIB = 8
+
+// ReadImage returns the image on the clipboard, preferring the PNG format
+// browsers and screenshot tools register over the plain device-independent
+// bitmap.
+func ReadImage() (image.Image, error) {
+	ret, _, err := procOpenClipboard.Call(0)
+	if ret == 0 {
+		return nil, fmt.Errorf("failed to open clipboard: %w", err)
+	}
+	defer procCloseClipboard.Call()
+
+	name, _ := syscall.UTF16PtrFromString("PNG")
+	pngFormat, _, _ := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(name)))
+	if data, ok := clipboardData(pngFormat); ok {
+		return png.Decode(bytes.NewReader(data))
+	}
+
+	if data, ok := clipboardData(CF_DIB); ok {
+		return decodeDIB(data)
+	}
+	return nil, ErrNoImage
+}
+
+func clipboardData(format uintptr) ([]byte, bool) {
+	if format == 0 {
+		return nil, false
+	}
+	available, _, _ := procIsClipboardFormatAvailable.Call(format)
+	if available == 0 {
+		return nil, false
+	}
+
+	handle, _, _ := procGetClipboardData.Call(format)
+	if handle == 0 {
+		return nil, false
+	}
+	size, _, _ := procGlobalSize.Call(handle)
+	ptr, _, _ := procGlobalLock.Call(handle)
+	if ptr == 0 || size == 0 {
+		return nil, false
+	}
+	defer procGlobalUnlock.Call(handle)
+
+	data := make([]byte, size)
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&data[0])), ptr, size)
+	return data, true
+}
+
+// decodeDIB prepends a BMP file header to a CF_DIB block and decodes it.
+func decodeDIB(dib []byte) (image.Image, error) {
+	if len(dib) < 40 {
+		return nil, fmt.Errorf("clipboard bitmap is too short")
+	}
+
+	headerSize := binary.LittleEndian.Uint32(dib[0:4])
+	bitCount := binary.LittleEndian.Uint16(dib[14:16])
+	compression := binary.LittleEndian.Uint32(dib[16:20])
+	colorsUsed := binary.LittleEndian.Uint32(dib[32:36])
+
+	// A 40 byte header with BI_BITFIELDS is followed by three color masks.
+	// Screenshots use the default masks, so treat it as plain BI_RGB.
+	if headerSize == 40 && compression == 3 && len(dib) >= 52 {
+		dib = append(append([]byte{}, dib[:40]...), dib[52:]...)
+		binary.LittleEndian.PutUint32(dib[16:20], 0)
+	}
+
+	paletteSize := colorsUsed * 4
+	if bitCount <= 8 && colorsUsed == 0 {
+		paletteSize = (1 << bitCount) * 4
+	}
+
+	fileHeader := make([]byte, 14)
+	fileHeader[0], fileHeader[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(fileHeader[2:6], uint32(14+len(dib)))
+	binary.LittleEndian.PutUint32(fileHeader[10:14], 14+headerSize+paletteSize)
+
+	return bmp.Decode(bytes.NewReader(append(fileHeader, dib...)))
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package elevation
+
+import (
+	"errors"
+	"go-sing/config"
+	"os"
+)
+
+// Supported reports whether sing-box can be launched through a UAC prompt.
+// Elsewhere go-sing itself needs the privileges sing-box requires.
+const Supported = false
+
+var errUnsupported = errors.New("elevation is only supported on Windows, run go-sing with the required privileges instead")
+
+func IsGoSingElevated() bool {
+	return os.Geteuid() == 0
+}
+
+func LaunchSingBoxElevated(paths *config.Paths, configPath string) error {
+	return errUnsupported
+}
+
+func KillSingBoxProcessElevated() error {
+	return errUnsupported
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"go-sing/config"
 	"os"
-	"path/filepath"
 	"syscall"
 	"unsafe"
 )
@@ -16,6 +15,9 @@ var (
 	procShellExecuteW       = shell32.NewProc("ShellExecuteW")
 )
 
+// Supported reports whether sing-box can be launched through a UAC prompt.
+const Supported = true
+
 const (
 	TOKEN_QUERY            = 0x0008
 	TokenElevationType     = 18
@@ -24,13 +26,6 @@ const (
 	SW_SHOW                = 5
 )
 
-func rootify(p string) string {
-	if len(p) == 2 && p[1] == ':' {
-		return p + `\`
-	}
-	return p
-}
-
 func IsGoSingElevated() bool {
 	handle, err := syscall.GetCurrentProcess()
 	if err != nil {
@@ -114,12 +109,10 @@ func RunElevated(program string, args string, workingDir string, showWindow bool
 	return nil
 }
 
-func LaunchSingBoxElevated(appDir string) error {
-	dataDir := filepath.Join(appDir, config.GoSingDataDir)
-	singBoxPath := filepath.Join(dataDir, config.SingBoxExeName)
-	configPath := filepath.Join(dataDir, config.SingBoxConfigFile)
-	logsDir := filepath.Join(dataDir, config.SingBoxLogDir)
-	logFilePath := filepath.Join(logsDir, config.SingBoxLogFile)
+func LaunchSingBoxElevated(paths *config.Paths, configPath string) error {
+	singBoxPath := paths.SingBoxExe()
+	logsDir := paths.LogsDir()
+	logFilePath := paths.SingBoxLog()
 
 	if _, err := os.Stat(singBoxPath); os.IsNotExist(err) {
 		return fmt.Errorf("sing-box.exe not found at: %s", singBoxPath)
@@ -138,9 +131,9 @@ func LaunchSingBoxElevated(appDir string) error {
 	}
 
 	cmdArgs := fmt.Sprintf("/C \"\"%s\" run -c \"%s\" -D \"%s\" > \"%s\" 2>&1\"",
-		singBoxPath, configPath, rootify(appDir), logFilePath)
+		singBoxPath, configPath, paths.DataDir, logFilePath)
 
-	return RunElevated("cmd.exe", cmdArgs, appDir, false)
+	return RunElevated("cmd.exe", cmdArgs, paths.DataDir, false)
 }
 
 func KillSingBoxProcessElevated() error {
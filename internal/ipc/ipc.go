@@ -0,0 +1,85 @@
+// Package ipc lets other go-sing processes control the running instance over
+// a Unix domain socket in the data directory. Requests carry a per-session
+// token stored next to the socket and readable only by the current user.
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sing/config"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	socketFile = "go-sing.sock"
+	tokenFile  = "ipc.token"
+
+	dialTimeout    = 2 * time.Second
+	requestTimeout = 30 * time.Second
+)
+
+var (
+	ErrAlreadyRunning = errors.New("another go-sing instance is running")
+	ErrNotRunning     = errors.New("no running go-sing instance")
+	ErrUnauthorized   = errors.New("unauthorized")
+)
+
+type Request struct {
+	Token   string   `json:"token"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+type Response struct {
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Handler runs a forwarded command. The result is sent back as JSON.
+type Handler func(command string, args []string) (any, error)
+
+// Call sends a command to the running instance and decodes its result into
+// result, if non-nil. It returns ErrNotRunning when no instance is listening.
+func Call(paths *config.Paths, command string, args []string, result any) error {
+	token, err := os.ReadFile(paths.File(tokenFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotRunning
+		}
+		return fmt.Errorf("failed to read IPC token: %w", err)
+	}
+
+	conn, err := net.DialTimeout("unix", paths.File(socketFile), dialTimeout)
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	request := Request{
+		Token:   string(token),
+		Command: command,
+		Args:    args,
+	}
+	err = json.NewEncoder(conn).Encode(request)
+	if err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	var response Response
+	err = json.NewDecoder(conn).Decode(&response)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+	if result != nil && len(response.Result) > 0 {
+		return json.Unmarshal(response.Result, result)
+	}
+	return nil
+}
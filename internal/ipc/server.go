@@ -0,0 +1,121 @@
+package ipc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-sing/config"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+type Server struct {
+	listener net.Listener
+	paths    *config.Paths
+	token    string
+	once     sync.Once
+}
+
+// Listen claims the IPC socket for this process. It returns ErrAlreadyRunning
+// when another instance is already answering on it.
+func Listen(paths *config.Paths) (*Server, error) {
+	socketPath := paths.File(socketFile)
+
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err == nil {
+		conn.Close()
+		return nil, ErrAlreadyRunning
+	}
+
+	// Left behind by an instance that did not shut down cleanly
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	os.Chmod(socketPath, 0600)
+
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to generate IPC token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	err = os.WriteFile(paths.File(tokenFile), []byte(token), 0600)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to write IPC token: %w", err)
+	}
+
+	return &Server{
+		listener: listener,
+		paths:    paths,
+		token:    token,
+	}, nil
+}
+
+// Serve accepts connections in the background until Close is called.
+func (s *Server) Serve(handler Handler) {
+	go func() {
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn, handler)
+		}
+	}()
+}
+
+func (s *Server) handle(conn net.Conn, handler Handler) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	encoder := json.NewEncoder(conn)
+
+	var request Request
+	err := json.NewDecoder(conn).Decode(&request)
+	if err != nil {
+		encoder.Encode(Response{Error: "invalid request"})
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(request.Token), []byte(s.token)) != 1 {
+		encoder.Encode(Response{Error: ErrUnauthorized.Error()})
+		return
+	}
+
+	result, err := handler(request.Command, request.Args)
+	if err != nil {
+		encoder.Encode(Response{Error: err.Error()})
+		return
+	}
+
+	var response Response
+	if result != nil {
+		response.Result, err = json.Marshal(result)
+		if err != nil {
+			encoder.Encode(Response{Error: fmt.Sprintf("failed to encode result: %v", err)})
+			return
+		}
+	}
+	encoder.Encode(response)
+}
+
+// Close stops accepting commands and removes the socket and token.
+func (s *Server) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.listener.Close()
+		os.Remove(s.paths.File(tokenFile))
+		os.Remove(s.paths.File(socketFile))
+	})
+	return err
+}
@@ -0,0 +1,264 @@
+// Package logrotate writes size- and age-limited log files and keeps their
+// compressed archives within a retention policy.
+package logrotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	timestampFormat = "20060102_150405"
+	compressedExt   = ".gz"
+)
+
+// pruneLocks holds a mutex per log path, so a prune after rotation cannot
+// compress or remove the same archives as one started at launch.
+var pruneLocks sync.Map
+
+// Policy limits a log file and its archives. Zero fields are not enforced.
+type Policy struct {
+	// MaxSize and MaxAge trigger rotation of the active file.
+	MaxSize int64
+	MaxAge  time.Duration
+	// MaxArchives and MaxTotalSize bound the archives kept next to it.
+	MaxArchives  int
+	MaxTotalSize int64
+}
+
+// File is a log file that is archived and replaced once it grows past the
+// policy limits. It is safe for concurrent use.
+type File struct {
+	path   string
+	policy Policy
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// Open archives any log left at path by a previous run and starts a new
+// file. Call Prune to enforce the retention policy on the archives.
+func Open(path string, policy Policy) (*File, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	_, err = Archive(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{path: path, policy: policy}
+	err = f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file = file
+	f.size = 0
+	f.opened = time.Now()
+	return nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.size > 0 && f.needsRotation(int64(len(p))) {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *File) needsRotation(pending int64) bool {
+	if f.policy.MaxSize > 0 && f.size+pending > f.policy.MaxSize {
+		return true
+	}
+	return f.policy.MaxAge > 0 && time.Since(f.opened) > f.policy.MaxAge
+}
+
+func (f *File) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	_, err = Archive(f.path)
+	if err != nil {
+		return err
+	}
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	go Prune(f.path, f.policy)
+	return nil
+}
+
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Archive renames the log at path to a timestamped archive next to it and
+// returns the new path, or "" when there is no log.
+func Archive(path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	base := fmt.Sprintf("%s.%s", path, time.Now().Format(timestampFormat))
+	archivePath := base
+	for i := 1; exists(archivePath) || exists(archivePath+compressedExt); i++ {
+		archivePath = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	err := os.Rename(path, archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to archive log file: %w", err)
+	}
+	return archivePath, nil
+}
+
+// Compress gzips an archive in place, replacing it with a .gz file.
+func Compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log archive: %w", err)
+	}
+	defer src.Close()
+
+	dstPath := path + compressedExt
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed log archive: %w", err)
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to compress log archive: %w", err)
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// Prune compresses archives of the log at path that are still plain text,
+// then removes the oldest ones until the policy's count and total size
+// limits hold. Concurrent calls for the same path run one at a time.
+func Prune(path string, policy Policy) error {
+	lock, _ := pruneLocks.LoadOrStore(filepath.Clean(path), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	archives, err := Archives(path)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for i, archivePath := range archives {
+		if policy.MaxArchives > 0 && i >= policy.MaxArchives {
+			if err := remove(archivePath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(archivePath, compressedExt) {
+			if err := Compress(archivePath); err != nil {
+				return err
+			}
+			archivePath += compressedExt
+		}
+
+		info, err := os.Stat(archivePath)
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+		if policy.MaxTotalSize > 0 && total > policy.MaxTotalSize {
+			if err := remove(archivePath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Archives lists the archives of the log at path, newest first.
+func Archives(path string) ([]string, error) {
+	dir, name := filepath.Split(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log archives: %w", err)
+	}
+
+	var archives []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), name+".") {
+			archives = append(archives, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// Archive names end in a timestamp, so this puts the newest first.
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+	return archives, nil
+}
+
+func remove(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old log archive: %w", err)
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
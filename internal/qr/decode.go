@@ -0,0 +1,410 @@
+// Package qr decodes QR codes from images. It is meant for screenshots and
+// saved images, where the code is sharp and not viewed at a steep angle;
+// rotation and scaling are handled, perspective distortion is not. Scaled
+// codes need modules of about two pixels or more.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+var ErrNotFound = errors.New("no QR code found in image")
+
+// Decode returns the text of the first QR code found in img.
+func Decode(img image.Image) (string, error) {
+	b := binarize(img)
+
+	text, err := decodeBitmap(b)
+	if err == nil {
+		return text, nil
+	}
+
+	// Light-on-dark codes
+	for i := range b.dark {
+		b.dark[i] = !b.dark[i]
+	}
+	if text, invertedErr := decodeBitmap(b); invertedErr == nil {
+		return text, nil
+	}
+	return "", err
+}
+
+func decodeBitmap(b *bitmap) (string, error) {
+	err := ErrNotFound
+	for _, triple := range findFinderPatterns(b) {
+		text, tripleErr := decodeAt(b, triple.topLeft, triple.topRight, triple.bottomLeft)
+		if tripleErr == nil {
+			return text, nil
+		}
+		// Report why the likeliest triple failed
+		if err == ErrNotFound {
+			err = tripleErr
+		}
+	}
+	return "", err
+}
+
+// decodeAt reads the code whose finder patterns are at the given positions.
+func decodeAt(b *bitmap, topLeft, topRight, bottomLeft finderPattern) (string, error) {
+	moduleSize := (topLeft.moduleSize + topRight.moduleSize + bottomLeft.moduleSize) / 3
+	modules := (distance(topLeft.point, topRight.point) + distance(topLeft.point, bottomLeft.point)) / 2 / moduleSize
+	estimate := modules + 7
+
+	// Run lengths misjudge the module size by up to a pixel, which adds up
+	// over large codes at small scales, so try the nearby valid dimensions
+	// from the closest on
+	var dimensions []int
+	for version := 1; version <= 40; version++ {
+		dimension := 17 + 4*version
+		if math.Abs(float64(dimension)-estimate) <= estimate/10+4 {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		return math.Abs(float64(dimensions[i])-estimate) < math.Abs(float64(dimensions[j])-estimate)
+	})
+
+	err := ErrNotFound
+	for _, dimension := range dimensions {
+		grid, ok := sampleGrid(b, topLeft.point, topRight.point, bottomLeft.point, dimension)
+		if !ok {
+			continue
+		}
+
+		text, gridErr := decodeGrid(grid, dimension)
+		if gridErr == nil {
+			return text, nil
+		}
+		// Report why the closest dimension failed
+		if err == ErrNotFound {
+			err = gridErr
+		}
+	}
+	return "", err
+}
+
+// binarize converts img to dark/light pixels using Otsu's threshold.
+// Transparent pixels count as light.
+func binarize(img image.Image) *bitmap {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	luminance := make([]uint8, width*height)
+
+	var histogram [256]int
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, bl, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// RGBA is alpha-premultiplied, so adding 0xffff-a composites on white
+			l := (299*r + 587*g + 114*bl) / 1000
+			l += 0xffff - a
+			value := uint8(min(l, 0xffff) >> 8)
+			luminance[y*width+x] = value
+			histogram[value]++
+		}
+	}
+
+	threshold := otsuThreshold(histogram, width*height)
+	b := &bitmap{width: width, height: height, dark: make([]bool, width*height)}
+	for i, l := range luminance {
+		b.dark[i] = l <= threshold
+	}
+	return b
+}
+
+func otsuThreshold(histogram [256]int, total int) uint8 {
+	var sum float64
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+
+	var sumBackground, best float64
+	var weightBackground int
+	var threshold uint8
+	for i, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sum - sumBackground) / float64(weightForeground)
+		between := float64(weightBackground) * float64(weightForeground) * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if between > best {
+			best = between
+			threshold = uint8(i)
+		}
+	}
+	return threshold
+}
+
+// sampleGrid reads the module at the center of every cell, mapping module
+// coordinates to pixels through the affine transform fixed by the three
+// finder pattern centers.
+func sampleGrid(b *bitmap, topLeft, topRight, bottomLeft point, dimension int) ([][]bool, bool) {
+	span := float64(dimension - 7)
+	grid := make([][]bool, dimension)
+	for y := 0; y < dimension; y++ {
+		grid[y] = make([]bool, dimension)
+		v := (float64(y) + 0.5 - 3.5) / span
+		for x := 0; x < dimension; x++ {
+			u := (float64(x) + 0.5 - 3.5) / span
+			px := topLeft.x + u*(topRight.x-topLeft.x) + v*(bottomLeft.x-topLeft.x)
+			py := topLeft.y + u*(topRight.y-topLeft.y) + v*(bottomLeft.y-topLeft.y)
+
+			ix, iy := int(math.Floor(px)), int(math.Floor(py))
+			if ix < 0 || iy < 0 || ix >= b.width || iy >= b.height {
+				return nil, false
+			}
+			grid[y][x] = b.at(ix, iy)
+		}
+	}
+	return grid, true
+}
+
+func decodeGrid(grid [][]bool, dimension int) (string, error) {
+	version := (dimension - 17) / 4
+
+	level, mask, err := readFormat(grid, dimension)
+	if err != nil {
+		return "", err
+	}
+
+	function := functionPatterns(version, dimension)
+	codewords := readCodewords(grid, function, mask, dimension)
+
+	data, err := correctBlocks(codewords, versionBlocks[version-1][level])
+	if err != nil {
+		return "", err
+	}
+
+	return decodeSegments(data, version)
+}
+
+// readFormat returns the error correction level index (L, M, Q, H order) and
+// the data mask from the format information next to the finder patterns.
+func readFormat(grid [][]bool, dimension int) (level, mask int, err error) {
+	bit := func(x, y int, bits int) int {
+		if grid[y][x] {
+			return bits<<1 | 1
+		}
+		return bits << 1
+	}
+
+	first := 0
+	for x := 0; x < 6; x++ {
+		first = bit(x, 8, first)
+	}
+	first = bit(7, 8, first)
+	first = bit(8, 8, first)
+	first = bit(8, 7, first)
+	for y := 5; y >= 0; y-- {
+		first = bit(8, y, first)
+	}
+
+	second := 0
+	for y := dimension - 1; y >= dimension-7; y-- {
+		second = bit(8, y, second)
+	}
+	for x := dimension - 8; x < dimension; x++ {
+		second = bit(x, 8, second)
+	}
+
+	bestDistance, bestData := 16, -1
+	for data := 0; data < 32; data++ {
+		code := formatCode(data)
+		for _, bits := range []int{first, second} {
+			if d := popcount(code ^ bits); d < bestDistance {
+				bestDistance, bestData = d, data
+			}
+		}
+	}
+	if bestDistance > 3 {
+		return 0, 0, fmt.Errorf("unreadable format information")
+	}
+
+	// Level bits are 01=L, 00=M, 11=Q, 10=H
+	level = [4]int{1, 0, 3, 2}[bestData>>3]
+	return level, bestData & 7, nil
+}
+
+// formatCode returns the masked BCH(15,5) code word for 5 bits of format data.
+func formatCode(data int) int {
+	code := data << 10
+	for i := 14; i >= 10; i-- {
+		if code&(1<<i) != 0 {
+			code ^= 0x537 << (i - 10)
+		}
+	}
+	return (data<<10 | code) ^ 0x5412
+}
+
+func popcount(x int) int {
+	n := 0
+	for ; x != 0; x &= x - 1 {
+		n++
+	}
+	return n
+}
+
+// functionPatterns marks the modules that do not carry data.
+func functionPatterns(version, dimension int) [][]bool {
+	function := make([][]bool, dimension)
+	for y := range function {
+		function[y] = make([]bool, dimension)
+	}
+	region := func(left, top, width, height int) {
+		for y := top; y < top+height; y++ {
+			for x := left; x < left+width; x++ {
+				function[y][x] = true
+			}
+		}
+	}
+
+	// Finder patterns with separators and format information
+	region(0, 0, 9, 9)
+	region(dimension-8, 0, 8, 9)
+	region(0, dimension-8, 9, 8)
+
+	centers := alignmentCenters[version]
+	last := len(centers) - 1
+	for i, cy := range centers {
+		for j, cx := range centers {
+			if (i == 0 && (j == 0 || j == last)) || (i == last && j == 0) {
+				continue
+			}
+			region(cx-2, cy-2, 5, 5)
+		}
+	}
+
+	// Timing patterns
+	region(6, 9, 1, dimension-17)
+	region(9, 6, dimension-17, 1)
+
+	if version > 6 {
+		region(dimension-11, 0, 3, 6)
+		region(0, dimension-11, 6, 3)
+	}
+	return function
+}
+
+func masked(mask, row, column int) bool {
+	switch mask {
+	case 0:
+		return (row+column)%2 == 0
+	case 1:
+		return row%2 == 0
+	case 2:
+		return column%3 == 0
+	case 3:
+		return (row+column)%3 == 0
+	case 4:
+		return (row/2+column/3)%2 == 0
+	case 5:
+		return (row*column)%2+(row*column)%3 == 0
+	case 6:
+		return ((row*column)%2+(row*column)%3)%2 == 0
+	default:
+		return ((row+column)%2+(row*column)%3)%2 == 0
+	}
+}
+
+// readCodewords reads the data modules in the two-column zigzag order.
+func readCodewords(grid, function [][]bool, mask, dimension int) []byte {
+	var codewords []byte
+	var current byte
+	bits := 0
+	upward := true
+
+	for right := dimension - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for count := 0; count < dimension; count++ {
+			y := count
+			if upward {
+				y = dimension - 1 - count
+			}
+			for column := 0; column < 2; column++ {
+				x := right - column
+				if function[y][x] {
+					continue
+				}
+
+				current <<= 1
+				if grid[y][x] != masked(mask, y, x) {
+					current |= 1
+				}
+				bits++
+				if bits == 8 {
+					codewords = append(codewords, current)
+					current, bits = 0, 0
+				}
+			}
+		}
+		upward = !upward
+	}
+	return codewords
+}
+
+// correctBlocks de-interleaves the codewords into their error correction
+// blocks, corrects each one and returns the concatenated data codewords.
+func correctBlocks(codewords []byte, layout ecBlocks) ([]byte, error) {
+	var blocks [][]byte
+	var dataLengths []int
+	for _, group := range layout.groups {
+		for i := 0; i < group.count; i++ {
+			blocks = append(blocks, make([]byte, 0, group.dataCodewords+layout.ecCodewordsPerBlock))
+			dataLengths = append(dataLengths, group.dataCodewords)
+		}
+	}
+
+	offset := 0
+	next := func() (byte, bool) {
+		if offset >= len(codewords) {
+			return 0, false
+		}
+		offset++
+		return codewords[offset-1], true
+	}
+
+	maxData := dataLengths[len(dataLengths)-1]
+	for i := 0; i < maxData; i++ {
+		for j := range blocks {
+			if i >= dataLengths[j] {
+				continue
+			}
+			c, ok := next()
+			if !ok {
+				return nil, fmt.Errorf("not enough codewords")
+			}
+			blocks[j] = append(blocks[j], c)
+		}
+	}
+	for i := 0; i < layout.ecCodewordsPerBlock; i++ {
+		for j := range blocks {
+			c, ok := next()
+			if !ok {
+				return nil, fmt.Errorf("not enough codewords")
+			}
+			blocks[j] = append(blocks[j], c)
+		}
+	}
+
+	var data []byte
+	for j, block := range blocks {
+		if err := correct(block, layout.ecCodewordsPerBlock); err != nil {
+			return nil, err
+		}
+		data = append(data, block[:dataLengths[j]]...)
+	}
+	return data, nil
+}
@@ -0,0 +1,278 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"strings"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var levels = []struct {
+	name  string
+	level qrcode.RecoveryLevel
+}{
+	{"L", qrcode.Low},
+	{"M", qrcode.Medium},
+	{"Q", qrcode.High},
+	{"H", qrcode.Highest},
+}
+
+// encode renders content as a QR code of the given version, or the smallest
+// that fits when version is 0. A negative size sets the pixels per module.
+func encode(t *testing.T, content string, version int, level qrcode.RecoveryLevel, size int) image.Image {
+	t.Helper()
+
+	var code *qrcode.QRCode
+	var err error
+	if version == 0 {
+		code, err = qrcode.New(content, level)
+	} else {
+		code, err = qrcode.NewWithForcedVersion(content, version, level)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return code.Image(size)
+}
+
+func assertDecodes(t *testing.T, img image.Image, want string) {
+	t.Helper()
+
+	got, err := Decode(img)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecodeLevels(t *testing.T) {
+	content := "sing-box://import-remote-profile?url=https%3A%2F%2Fexample.com%2Fsub#Example"
+	for _, level := range levels {
+		t.Run(level.name, func(t *testing.T) {
+			assertDecodes(t, encode(t, content, 0, level.level, -4), content)
+		})
+	}
+}
+
+func TestDecodeVersions(t *testing.T) {
+	for version := 1; version <= 40; version++ {
+		for _, level := range levels {
+			t.Run(fmt.Sprintf("%d-%s", version, level.name), func(t *testing.T) {
+				content := fmt.Sprint(version)
+				assertDecodes(t, encode(t, content, version, level.level, -3), content)
+			})
+		}
+	}
+}
+
+func TestDecodeScales(t *testing.T) {
+	tests := []struct {
+		version int
+		size    int
+	}{
+		{1, -1},
+		{1, -2},
+		{5, -10},
+		{10, 256},
+		{10, 300},
+		{20, 300},
+		{23, 373},
+		{27, 300},
+		{28, 300},
+		{33, 373},
+		{27, 512},
+		{40, 800},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("v%d-%dpx", tt.version, tt.size), func(t *testing.T) {
+			content := "HTTPS://EXAMPLE.COM"
+			assertDecodes(t, encode(t, content, tt.version, qrcode.Low, tt.size), content)
+		})
+	}
+}
+
+func TestDecodeSegments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"numeric", "01234567890123456789"},
+		{"alphanumeric", "HTTPS://EXAMPLE.COM/ABC-123"},
+		{"bytes", "vless://uuid@example.com:443?security=reality#Server"},
+		{"utf-8", "https://example.com/профиль"},
+		{"long", strings.Repeat("https://example.com/", 50)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertDecodes(t, encode(t, tt.content, 0, qrcode.Medium, -4), tt.content)
+		})
+	}
+}
+
+func TestDecodeRotated(t *testing.T) {
+	content := "https://example.com/rotated"
+	img := encode(t, content, 0, qrcode.Medium, -4)
+
+	for turns := 1; turns <= 3; turns++ {
+		img = rotate90(img)
+		t.Run(fmt.Sprintf("%d", turns*90), func(t *testing.T) {
+			assertDecodes(t, img, content)
+		})
+	}
+}
+
+func TestDecodeEmbedded(t *testing.T) {
+	content := "https://example.com/embedded"
+	code := encode(t, content, 0, qrcode.Medium, -5)
+
+	canvas := image.NewRGBA(image.Rect(0, 0, 900, 700))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{230, 230, 240, 255}), image.Point{}, draw.Src)
+	// Window chrome and text around the code
+	draw.Draw(canvas, image.Rect(0, 0, 900, 40), image.NewUniform(color.RGBA{40, 40, 60, 255}), image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(60, 500, 400, 520), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	offset := image.Pt(413, 121)
+	draw.Draw(canvas, code.Bounds().Add(offset), code, image.Point{}, draw.Src)
+
+	assertDecodes(t, canvas, content)
+}
+
+func TestDecodeInverted(t *testing.T) {
+	content := "https://example.com/inverted"
+	code := encode(t, content, 0, qrcode.Medium, -4)
+
+	bounds := code.Bounds()
+	inverted := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.GrayModel.Convert(code.At(x, y)).(color.Gray)
+			inverted.SetGray(x, y, color.Gray{Y: 255 - gray.Y})
+		}
+	}
+
+	assertDecodes(t, inverted, content)
+}
+
+func TestDecodeGarbage(t *testing.T) {
+	noise := image.NewGray(image.Rect(0, 0, 200, 200))
+	random := rand.New(rand.NewSource(1))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(random.Intn(256))
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"empty", image.NewGray(image.Rect(0, 0, 0, 0))},
+		{"blank", image.NewGray(image.Rect(0, 0, 100, 100))},
+		{"noise", noise},
+		{"cropped", cropped(encode(t, "https://example.com", 0, qrcode.Medium, -4))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.img)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.name != "cropped" && !errors.Is(err, ErrNotFound) {
+				t.Fatalf("got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func rotate90(img image.Image) image.Image {
+	bounds := img.Bounds()
+	rotated := image.NewRGBA(image.Rect(0, 0, bounds.Dy(), bounds.Dx()))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			rotated.Set(bounds.Max.Y-1-y, x-bounds.Min.X, img.At(x, y))
+		}
+	}
+	return rotated
+}
+
+// cropped cuts off the right half of img, taking one finder pattern with it.
+func cropped(img image.Image) image.Image {
+	bounds := img.Bounds()
+	half := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/2, bounds.Dy()))
+	draw.Draw(half, half.Bounds(), img, bounds.Min, draw.Src)
+	return half
+}
+
+func TestDecodeSegmentsTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"FNC1", []byte{0x90}},
+		{"ECI", []byte{0x70}},
+		{"wide ECI", []byte{0x7c, 0x00}},
+		{"structured append", []byte{0x30}},
+		{"count", []byte{0x40}},
+		{"bytes", []byte{0x40, 0x50}},
+		{"numeric", []byte{0x10, 0x0c}},
+		{"alphanumeric", []byte{0x20, 0x18}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeSegments(tt.data, 1)
+			if !errors.Is(err, errTruncated) {
+				t.Fatalf("got %v, want errTruncated", err)
+			}
+		})
+	}
+}
+
+func FuzzDecodeSegments(f *testing.F) {
+	f.Add([]byte{0x90}, 1)
+	f.Add([]byte{0x7c, 0x00}, 1)
+	f.Add([]byte{0x40, 0x36, 0x87, 0x47, 0x47, 0x07, 0x30, 0xec}, 1)
+	f.Add([]byte{0x10, 0x0c, 0x56, 0x61, 0x80}, 10)
+
+	f.Fuzz(func(t *testing.T, data []byte, version int) {
+		if version < 1 || version > 40 {
+			return
+		}
+		decodeSegments(data, version)
+	})
+}
+
+// FuzzDecode decodes a real code with some of its pixels flipped, which
+// reaches the format, codeword and segment decoding with damaged input.
+func FuzzDecode(f *testing.F) {
+	code, err := qrcode.New("https://example.com/fuzz", qrcode.Low)
+	if err != nil {
+		f.Fatalf("failed to encode: %v", err)
+	}
+	img := code.Image(-2)
+	bounds := img.Bounds()
+
+	f.Add([]byte{})
+	f.Add([]byte{0x12, 0x34, 0x56, 0x78})
+
+	f.Fuzz(func(t *testing.T, flips []byte) {
+		damaged := image.NewGray(bounds)
+		draw.Draw(damaged, bounds, img, bounds.Min, draw.Src)
+		for i := 0; i+1 < len(flips); i += 2 {
+			x := int(flips[i]) % bounds.Dx()
+			y := int(flips[i+1]) % bounds.Dy()
+			offset := damaged.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			damaged.Pix[offset] = 255 - damaged.Pix[offset]
+		}
+		Decode(damaged)
+	})
+}
@@ -0,0 +1,279 @@
+package qr
+
+import (
+	"math"
+	"sort"
+)
+
+// bitmap is a binarized image, true for dark pixels.
+type bitmap struct {
+	width, height int
+	dark          []bool
+}
+
+func (b *bitmap) at(x, y int) bool {
+	return b.dark[y*b.width+x]
+}
+
+type point struct {
+	x, y float64
+}
+
+func distance(a, b point) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+// maxFinderTriples bounds how many candidate triples are tried, as data
+// modules can happen to look like finder patterns.
+const maxFinderTriples = 5
+
+type finderPattern struct {
+	point
+	moduleSize float64
+	count      int
+}
+
+// finderTriple is a possible set of the three finder patterns of a code.
+type finderTriple struct {
+	topLeft, topRight, bottomLeft finderPattern
+	score                         float64
+}
+
+// findFinderPatterns scans for the 1:1:3:1:1 dark/light runs of the three
+// finder patterns and returns the likeliest triples, best first.
+func findFinderPatterns(b *bitmap) []finderTriple {
+	var candidates []*finderPattern
+
+	for y := 0; y < b.height; y++ {
+		var counts [5]int
+		state := 0
+		for x := 0; x < b.width; x++ {
+			if b.at(x, y) {
+				if state%2 == 1 {
+					state++
+				}
+				counts[state]++
+				continue
+			}
+
+			if state%2 == 1 {
+				counts[state]++
+				continue
+			}
+			if state == 0 && counts[0] == 0 {
+				continue
+			}
+			if state == 4 {
+				if isFinderRatio(counts) {
+					candidates = addCandidate(candidates, b, counts, x, y)
+				}
+				counts = [5]int{counts[2], counts[3], counts[4], 1, 0}
+				state = 3
+				continue
+			}
+			state++
+			counts[state]++
+		}
+		if state == 4 && isFinderRatio(counts) {
+			candidates = addCandidate(candidates, b, counts, b.width, y)
+		}
+	}
+
+	return selectFinderPatterns(candidates)
+}
+
+func isFinderRatio(counts [5]int) bool {
+	total := 0
+	for _, count := range counts {
+		if count == 0 {
+			return false
+		}
+		total += count
+	}
+	if total < 7 {
+		return false
+	}
+
+	module := float64(total) / 7
+	tolerance := module / 2
+	return math.Abs(module-float64(counts[0])) < tolerance &&
+		math.Abs(module-float64(counts[1])) < tolerance &&
+		math.Abs(3*module-float64(counts[2])) < 3*tolerance &&
+		math.Abs(module-float64(counts[3])) < tolerance &&
+		math.Abs(module-float64(counts[4])) < tolerance
+}
+
+func centerFromEnd(counts [5]int, end int) float64 {
+	return float64(end-counts[4]-counts[3]) - float64(counts[2])/2
+}
+
+func addCandidate(candidates []*finderPattern, b *bitmap, counts [5]int, endX, y int) []*finderPattern {
+	total := counts[0] + counts[1] + counts[2] + counts[3] + counts[4]
+	centerX := centerFromEnd(counts, endX)
+
+	centerY, ok := crossCheck(b, int(centerX), y, counts[2], total, false)
+	if !ok {
+		return candidates
+	}
+	centerX, ok = crossCheck(b, int(centerX), int(centerY), counts[2], total, true)
+	if !ok {
+		return candidates
+	}
+
+	moduleSize := float64(total) / 7
+	for _, c := range candidates {
+		if math.Abs(c.x-centerX) <= moduleSize && math.Abs(c.y-centerY) <= moduleSize &&
+			math.Abs(c.moduleSize-moduleSize) <= math.Max(1, c.moduleSize/2) {
+			n := float64(c.count)
+			c.x = (c.x*n + centerX) / (n + 1)
+			c.y = (c.y*n + centerY) / (n + 1)
+			c.moduleSize = (c.moduleSize*n + moduleSize) / (n + 1)
+			c.count++
+			return candidates
+		}
+	}
+
+	return append(candidates, &finderPattern{
+		point:      point{centerX, centerY},
+		moduleSize: moduleSize,
+		count:      1,
+	})
+}
+
+// crossCheck verifies a finder pattern through (x, y) along the other axis
+// and returns the refined center coordinate on that axis.
+func crossCheck(b *bitmap, x, y, maxCount, originalTotal int, horizontal bool) (float64, bool) {
+	pos, limit := y, b.height
+	darkAt := func(i int) bool { return b.at(x, i) }
+	if horizontal {
+		pos, limit = x, b.width
+		darkAt = func(i int) bool { return b.at(i, y) }
+	}
+
+	var counts [5]int
+	i := pos
+	for i >= 0 && darkAt(i) {
+		counts[2]++
+		i--
+	}
+	if i < 0 {
+		return 0, false
+	}
+	for i >= 0 && !darkAt(i) && counts[1] <= maxCount {
+		counts[1]++
+		i--
+	}
+	if i < 0 || counts[1] > maxCount {
+		return 0, false
+	}
+	for i >= 0 && darkAt(i) && counts[0] <= maxCount {
+		counts[0]++
+		i--
+	}
+	if counts[0] > maxCount {
+		return 0, false
+	}
+
+	i = pos + 1
+	for i < limit && darkAt(i) {
+		counts[2]++
+		i++
+	}
+	if i == limit {
+		return 0, false
+	}
+	for i < limit && !darkAt(i) && counts[3] <= maxCount {
+		counts[3]++
+		i++
+	}
+	if i == limit || counts[3] > maxCount {
+		return 0, false
+	}
+	for i < limit && darkAt(i) && counts[4] <= maxCount {
+		counts[4]++
+		i++
+	}
+	if counts[4] > maxCount {
+		return 0, false
+	}
+
+	total := counts[0] + counts[1] + counts[2] + counts[3] + counts[4]
+	if 5*abs(total-originalTotal) >= 2*originalTotal || !isFinderRatio(counts) {
+		return 0, false
+	}
+	return centerFromEnd(counts, i), true
+}
+
+// selectFinderPatterns picks the triples of candidates that best form the
+// right isosceles triangle of a QR code, with each triple's patterns ordered.
+func selectFinderPatterns(candidates []*finderPattern) []finderTriple {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].count > candidates[j].count
+	})
+	if len(candidates) > 10 {
+		candidates = candidates[:10]
+	}
+
+	var triples []finderTriple
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			for k := j + 1; k < len(candidates); k++ {
+				a, b, c := candidates[i], candidates[j], candidates[k]
+				minSize := math.Min(a.moduleSize, math.Min(b.moduleSize, c.moduleSize))
+				maxSize := math.Max(a.moduleSize, math.Max(b.moduleSize, c.moduleSize))
+				if maxSize > 1.4*minSize {
+					continue
+				}
+
+				sides := []float64{distance(a.point, b.point), distance(b.point, c.point), distance(a.point, c.point)}
+				sort.Float64s(sides)
+				if sides[0] < 7*minSize {
+					continue
+				}
+				score := math.Abs(sides[2]*sides[2]-sides[0]*sides[0]-sides[1]*sides[1])/(sides[2]*sides[2]) +
+					math.Abs(sides[1]-sides[0])/sides[1]
+				if score <= 0.3 {
+					triples = append(triples, orderFinderPatterns(*a, *b, *c, score))
+				}
+			}
+		}
+	}
+
+	sort.Slice(triples, func(i, j int) bool {
+		return triples[i].score < triples[j].score
+	})
+	if len(triples) > maxFinderTriples {
+		triples = triples[:maxFinderTriples]
+	}
+	return triples
+}
+
+// orderFinderPatterns tells the top-left, top-right and bottom-left patterns
+// of a triple apart.
+func orderFinderPatterns(a, b, c finderPattern, score float64) finderTriple {
+	var topLeft, topRight, bottomLeft finderPattern
+
+	// The top-left pattern is opposite the longest side
+	ab, bc, ac := distance(a.point, b.point), distance(b.point, c.point), distance(a.point, c.point)
+	switch {
+	case bc >= ab && bc >= ac:
+		topLeft, topRight, bottomLeft = a, b, c
+	case ac >= ab && ac >= bc:
+		topLeft, topRight, bottomLeft = b, a, c
+	default:
+		topLeft, topRight, bottomLeft = c, a, b
+	}
+
+	cross := (topRight.x-topLeft.x)*(bottomLeft.y-topLeft.y) - (topRight.y-topLeft.y)*(bottomLeft.x-topLeft.x)
+	if cross < 0 {
+		topRight, bottomLeft = bottomLeft, topRight
+	}
+	return finderTriple{topLeft, topRight, bottomLeft, score}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,149 @@
+package qr
+
+import "errors"
+
+var errTooManyErrors = errors.New("too many errors to correct")
+
+var gfExp [512]byte
+var gfLog [256]int
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+255-gfLog[b]]
+}
+
+func gfPow(power int) byte {
+	power %= 255
+	if power < 0 {
+		power += 255
+	}
+	return gfExp[power]
+}
+
+// evalLow evaluates a polynomial stored lowest degree first.
+func evalLow(p []byte, x byte) byte {
+	var y byte
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ p[i]
+	}
+	return y
+}
+
+// correct fixes up to ecCount/2 byte errors in block in place. block holds
+// the data codewords followed by ecCount error correction codewords, highest
+// degree first.
+func correct(block []byte, ecCount int) error {
+	n := len(block)
+
+	syndromes := make([]byte, ecCount)
+	hasErrors := false
+	for i := range syndromes {
+		x := gfPow(i)
+		var s byte
+		for _, c := range block {
+			s = gfMul(s, x) ^ c
+		}
+		syndromes[i] = s
+		if s != 0 {
+			hasErrors = true
+		}
+	}
+	if !hasErrors {
+		return nil
+	}
+
+	// Berlekamp-Massey, polynomials lowest degree first
+	locator := []byte{1}
+	prev := []byte{1}
+	length, shift := 0, 1
+	var prevDiscrepancy byte = 1
+	for k := 0; k < ecCount; k++ {
+		discrepancy := syndromes[k]
+		for i := 1; i <= length && i < len(locator); i++ {
+			discrepancy ^= gfMul(locator[i], syndromes[k-i])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		scale := gfDiv(discrepancy, prevDiscrepancy)
+		next := make([]byte, max(len(locator), len(prev)+shift))
+		copy(next, locator)
+		for i, c := range prev {
+			next[i+shift] ^= gfMul(scale, c)
+		}
+
+		if 2*length <= k {
+			prev = locator
+			length = k + 1 - length
+			prevDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	if 2*length > ecCount {
+		return errTooManyErrors
+	}
+
+	// Chien search for the error positions
+	var positions []int
+	for power := 0; power < n; power++ {
+		if evalLow(locator, gfPow(-power)) == 0 {
+			positions = append(positions, n-1-power)
+		}
+	}
+	if len(positions) != length {
+		return errTooManyErrors
+	}
+
+	// Forney: evaluator = syndromes * locator mod x^ecCount
+	evaluator := make([]byte, ecCount)
+	for i := 0; i < ecCount; i++ {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= gfMul(locator[j], syndromes[i-j])
+		}
+	}
+
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	for _, position := range positions {
+		x := gfPow(n - 1 - position)
+		xInverse := gfDiv(1, x)
+		denominator := evalLow(derivative, xInverse)
+		if denominator == 0 {
+			return errTooManyErrors
+		}
+		block[position] ^= gfMul(x, gfDiv(evalLow(evaluator, xInverse), denominator))
+	}
+	return nil
+}
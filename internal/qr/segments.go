@@ -0,0 +1,176 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const alphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+var errTruncated = errors.New("truncated segment")
+
+type bitReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *bitReader) available() int {
+	return len(r.data)*8 - r.offset
+}
+
+// read returns the next n bits, or errTruncated when fewer are left.
+func (r *bitReader) read(n int) (int, error) {
+	if n > r.available() {
+		return 0, errTruncated
+	}
+
+	value := 0
+	for i := 0; i < n; i++ {
+		bit := r.data[r.offset/8] >> (7 - r.offset%8) & 1
+		value = value<<1 | int(bit)
+		r.offset++
+	}
+	return value, nil
+}
+
+// countBits returns the width of the character count field for a mode.
+func countBits(mode, version int) int {
+	sizes := map[int][3]int{
+		0x1: {10, 12, 14},
+		0x2: {9, 11, 13},
+		0x4: {8, 16, 16},
+		0x8: {8, 10, 12},
+	}[mode]
+	switch {
+	case version <= 9:
+		return sizes[0]
+	case version <= 26:
+		return sizes[1]
+	default:
+		return sizes[2]
+	}
+}
+
+// decodeSegments decodes numeric, alphanumeric and byte segments. Byte
+// segments are assumed to be UTF-8, which covers URLs and share links.
+func decodeSegments(data []byte, version int) (string, error) {
+	r := &bitReader{data: data}
+	var text strings.Builder
+
+	for r.available() >= 4 {
+		mode, err := r.read(4)
+		if err != nil {
+			return "", err
+		}
+
+		switch mode {
+		case 0x0:
+			return text.String(), nil
+		case 0x3: // structured append
+			_, err = r.read(16)
+		case 0x5: // FNC1 first position
+		case 0x9: // FNC1 second position
+			_, err = r.read(8)
+		case 0x7: // ECI, the designator is skipped
+			err = skipECI(r)
+		case 0x1, 0x2, 0x4:
+			err = decodeSegment(r, mode, version, &text)
+		default:
+			return "", fmt.Errorf("unsupported QR segment mode %d", mode)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return text.String(), nil
+}
+
+func skipECI(r *bitReader) error {
+	bits := 7
+	for _, wider := range []int{14, 22} {
+		flag, err := r.read(1)
+		if err != nil {
+			return err
+		}
+		if flag == 0 {
+			break
+		}
+		bits = wider
+	}
+	_, err := r.read(bits)
+	return err
+}
+
+func decodeSegment(r *bitReader, mode, version int, text *strings.Builder) error {
+	count, err := r.read(countBits(mode, version))
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case 0x1:
+		return decodeNumeric(r, count, text)
+	case 0x2:
+		return decodeAlphanumeric(r, count, text)
+	}
+
+	if r.available() < 8*count {
+		return errTruncated
+	}
+	for i := 0; i < count; i++ {
+		value, err := r.read(8)
+		if err != nil {
+			return err
+		}
+		text.WriteByte(byte(value))
+	}
+	return nil
+}
+
+func decodeNumeric(r *bitReader, count int, text *strings.Builder) error {
+	for count > 0 {
+		digits, bits, limit := 3, 10, 1000
+		if count == 2 {
+			digits, bits, limit = 2, 7, 100
+		} else if count == 1 {
+			digits, bits, limit = 1, 4, 10
+		}
+		value, err := r.read(bits)
+		if err != nil {
+			return err
+		}
+		if value >= limit {
+			return fmt.Errorf("invalid numeric value")
+		}
+		fmt.Fprintf(text, "%0*d", digits, value)
+		count -= digits
+	}
+	return nil
+}
+
+func decodeAlphanumeric(r *bitReader, count int, text *strings.Builder) error {
+	for count > 1 {
+		value, err := r.read(11)
+		if err != nil {
+			return err
+		}
+		if value >= 45*45 {
+			return fmt.Errorf("invalid alphanumeric value")
+		}
+		text.WriteByte(alphanumericChars[value/45])
+		text.WriteByte(alphanumericChars[value%45])
+		count -= 2
+	}
+	if count == 1 {
+		value, err := r.read(6)
+		if err != nil {
+			return err
+		}
+		if value >= 45 {
+			return fmt.Errorf("invalid alphanumeric value")
+		}
+		text.WriteByte(alphanumericChars[value])
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package qr
+
+type blockGroup struct {
+	count         int
+	dataCodewords int
+}
+
+type ecBlocks struct {
+	ecCodewordsPerBlock int
+	groups              []blockGroup
+}
+
+// versionBlocks holds the error correction block layout for versions 1-40,
+// indexed by version-1 and then by level in L, M, Q, H order.
+var versionBlocks = [40][4]ecBlocks{
+	{ // 1
+		{7, []blockGroup{{1, 19}}},
+		{10, []blockGroup{{1, 16}}},
+		{13, []blockGroup{{1, 13}}},
+		{17, []blockGroup{{1, 9}}},
+	},
+	{ // 2
+		{10, []blockGroup{{1, 34}}},
+		{16, []blockGroup{{1, 28}}},
+		{22, []blockGroup{{1, 22}}},
+		{28, []blockGroup{{1, 16}}},
+	},
+	{ // 3
+		{15, []blockGroup{{1, 55}}},
+		{26, []blockGroup{{1, 44}}},
+		{18, []blockGroup{{2, 17}}},
+		{22, []blockGroup{{2, 13}}},
+	},
+	{ // 4
+		{20, []blockGroup{{1, 80}}},
+		{18, []blockGroup{{2, 32}}},
+		{26, []blockGroup{{2, 24}}},
+		{16, []blockGroup{{4, 9}}},
+	},
+	{ // 5
+		{26, []blockGroup{{1, 108}}},
+		{24, []blockGroup{{2, 43}}},
+		{18, []blockGroup{{2, 15}, {2, 16}}},
+		{22, []blockGroup{{2, 11}, {2, 12}}},
+	},
+	{ // 6
+		{18, []blockGroup{{2, 68}}},
+		{16, []blockGroup{{4, 27}}},
+		{24, []blockGroup{{4, 19}}},
+		{28, []blockGroup{{4, 15}}},
+	},
+	{ // 7
+		{20, []blockGroup{{2, 78}}},
+		{18, []blockGroup{{4, 31}}},
+		{18, []blockGroup{{2, 14}, {4, 15}}},
+		{26, []blockGroup{{4, 13}, {1, 14}}},
+	},
+	{ // 8
+		{24, []blockGroup{{2, 97}}},
+		{22, []blockGroup{{2, 38}, {2, 39}}},
+		{22, []blockGroup{{4, 18}, {2, 19}}},
+		{26, []blockGroup{{4, 14}, {2, 15}}},
+	},
+	{ // 9
+		{30, []blockGroup{{2, 116}}},
+		{22, []blockGroup{{3, 36}, {2, 37}}},
+		{20, []blockGroup{{4, 16}, {4, 17}}},
+		{24, []blockGroup{{4, 12}, {4, 13}}},
+	},
+	{ // 10
+		{18, []blockGroup{{2, 68}, {2, 69}}},
+		{26, []blockGroup{{4, 43}, {1, 44}}},
+		{24, []blockGroup{{6, 19}, {2, 20}}},
+		{28, []blockGroup{{6, 15}, {2, 16}}},
+	},
+	{ // 11
+		{20, []blockGroup{{4, 81}}},
+		{30, []blockGroup{{1, 50}, {4, 51}}},
+		{28, []blockGroup{{4, 22}, {4, 23}}},
+		{24, []blockGroup{{3, 12}, {8, 13}}},
+	},
+	{ // 12
+		{24, []blockGroup{{2, 92}, {2, 93}}},
+		{22, []blockGroup{{6, 36}, {2, 37}}},
+		{26, []blockGroup{{4, 20}, {6, 21}}},
+		{28, []blockGroup{{7, 14}, {4, 15}}},
+	},
+	{ // 13
+		{26, []blockGroup{{4, 107}}},
+		{22, []blockGroup{{8, 37}, {1, 38}}},
+		{24, []blockGroup{{8, 20}, {4, 21}}},
+		{22, []blockGroup{{12, 11}, {4, 12}}},
+	},
+	{ // 14
+		{30, []blockGroup{{3, 115}, {1, 116}}},
+		{24, []blockGroup{{4, 40}, {5, 41}}},
+		{20, []blockGroup{{11, 16}, {5, 17}}},
+		{24, []blockGroup{{11, 12}, {5, 13}}},
+	},
+	{ // 15
+		{22, []blockGroup{{5, 87}, {1, 88}}},
+		{24, []blockGroup{{5, 41}, {5, 42}}},
+		{30, []blockGroup{{5, 24}, {7, 25}}},
+		{24, []blockGroup{{11, 12}, {7, 13}}},
+	},
+	{ // 16
+		{24, []blockGroup{{5, 98}, {1, 99}}},
+		{28, []blockGroup{{7, 45}, {3, 46}}},
+		{24, []blockGroup{{15, 19}, {2, 20}}},
+		{30, []blockGroup{{3, 15}, {13, 16}}},
+	},
+	{ // 17
+		{28, []blockGroup{{1, 107}, {5, 108}}},
+		{28, []blockGroup{{10, 46}, {1, 47}}},
+		{28, []blockGroup{{1, 22}, {15, 23}}},
+		{28, []blockGroup{{2, 14}, {17, 15}}},
+	},
+	{ // 18
+		{30, []blockGroup{{5, 120}, {1, 121}}},
+		{26, []blockGroup{{9, 43}, {4, 44}}},
+		{28, []blockGroup{{17, 22}, {1, 23}}},
+		{28, []blockGroup{{2, 14}, {19, 15}}},
+	},
+	{ // 19
+		{28, []blockGroup{{3, 113}, {4, 114}}},
+		{26, []blockGroup{{3, 44}, {11, 45}}},
+		{26, []blockGroup{{17, 21}, {4, 22}}},
+		{26, []blockGroup{{9, 13}, {16, 14}}},
+	},
+	{ // 20
+		{28, []blockGroup{{3, 107}, {5, 108}}},
+		{26, []blockGroup{{3, 41}, {13, 42}}},
+		{30, []blockGroup{{15, 24}, {5, 25}}},
+		{28, []blockGroup{{15, 15}, {10, 16}}},
+	},
+	{ // 21
+		{28, []blockGroup{{4, 116}, {4, 117}}},
+		{26, []blockGroup{{17, 42}}},
+		{28, []blockGroup{{17, 22}, {6, 23}}},
+		{30, []blockGroup{{19, 16}, {6, 17}}},
+	},
+	{ // 22
+		{28, []blockGroup{{2, 111}, {7, 112}}},
+		{28, []blockGroup{{17, 46}}},
+		{30, []blockGroup{{7, 24}, {16, 25}}},
+		{24, []blockGroup{{34, 13}}},
+	},
+	{ // 23
+		{30, []blockGroup{{4, 121}, {5, 122}}},
+		{28, []blockGroup{{4, 47}, {14, 48}}},
+		{30, []blockGroup{{11, 24}, {14, 25}}},
+		{30, []blockGroup{{16, 15}, {14, 16}}},
+	},
+	{ // 24
+		{30, []blockGroup{{6, 117}, {4, 118}}},
+		{28, []blockGroup{{6, 45}, {14, 46}}},
+		{30, []blockGroup{{11, 24}, {16, 25}}},
+		{30, []blockGroup{{30, 16}, {2, 17}}},
+	},
+	{ // 25
+		{26, []blockGroup{{8, 106}, {4, 107}}},
+		{28, []blockGroup{{8, 47}, {13, 48}}},
+		{30, []blockGroup{{7, 24}, {22, 25}}},
+		{30, []blockGroup{{22, 15}, {13, 16}}},
+	},
+	{ // 26
+		{28, []blockGroup{{10, 114}, {2, 115}}},
+		{28, []blockGroup{{19, 46}, {4, 47}}},
+		{28, []blockGroup{{28, 22}, {6, 23}}},
+		{30, []blockGroup{{33, 16}, {4, 17}}},
+	},
+	{ // 27
+		{30, []blockGroup{{8, 122}, {4, 123}}},
+		{28, []blockGroup{{22, 45}, {3, 46}}},
+		{30, []blockGroup{{8, 23}, {26, 24}}},
+		{30, []blockGroup{{12, 15}, {28, 16}}},
+	},
+	{ // 28
+		{30, []blockGroup{{3, 117}, {10, 118}}},
+		{28, []blockGroup{{3, 45}, {23, 46}}},
+		{30, []blockGroup{{4, 24}, {31, 25}}},
+		{30, []blockGroup{{11, 15}, {31, 16}}},
+	},
+	{ // 29
+		{30, []blockGroup{{7, 116}, {7, 117}}},
+		{28, []blockGroup{{21, 45}, {7, 46}}},
+		{30, []blockGroup{{1, 23}, {37, 24}}},
+		{30, []blockGroup{{19, 15}, {26, 16}}},
+	},
+	{ // 30
+		{30, []blockGroup{{5, 115}, {10, 116}}},
+		{28, []blockGroup{{19, 47}, {10, 48}}},
+		{30, []blockGroup{{15, 24}, {25, 25}}},
+		{30, []blockGroup{{23, 15}, {25, 16}}},
+	},
+	{ // 31
+		{30, []blockGroup{{13, 115}, {3, 116}}},
+		{28, []blockGroup{{2, 46}, {29, 47}}},
+		{30, []blockGroup{{42, 24}, {1, 25}}},
+		{30, []blockGroup{{23, 15}, {28, 16}}},
+	},
+	{ // 32
+		{30, []blockGroup{{17, 115}}},
+		{28, []blockGroup{{10, 46}, {23, 47}}},
+		{30, []blockGroup{{10, 24}, {35, 25}}},
+		{30, []blockGroup{{19, 15}, {35, 16}}},
+	},
+	{ // 33
+		{30, []blockGroup{{17, 115}, {1, 116}}},
+		{28, []blockGroup{{14, 46}, {21, 47}}},
+		{30, []blockGroup{{29, 24}, {19, 25}}},
+		{30, []blockGroup{{11, 15}, {46, 16}}},
+	},
+	{ // 34
+		{30, []blockGroup{{13, 115}, {6, 116}}},
+		{28, []blockGroup{{14, 46}, {23, 47}}},
+		{30, []blockGroup{{44, 24}, {7, 25}}},
+		{30, []blockGroup{{59, 16}, {1, 17}}},
+	},
+	{ // 35
+		{30, []blockGroup{{12, 121}, {7, 122}}},
+		{28, []blockGroup{{12, 47}, {26, 48}}},
+		{30, []blockGroup{{39, 24}, {14, 25}}},
+		{30, []blockGroup{{22, 15}, {41, 16}}},
+	},
+	{ // 36
+		{30, []blockGroup{{6, 121}, {14, 122}}},
+		{28, []blockGroup{{6, 47}, {34, 48}}},
+		{30, []blockGroup{{46, 24}, {10, 25}}},
+		{30, []blockGroup{{2, 15}, {64, 16}}},
+	},
+	{ // 37
+		{30, []blockGroup{{17, 122}, {4, 123}}},
+		{28, []blockGroup{{29, 46}, {14, 47}}},
+		{30, []blockGroup{{49, 24}, {10, 25}}},
+		{30, []blockGroup{{24, 15}, {46, 16}}},
+	},
+	{ // 38
+		{30, []blockGroup{{4, 122}, {18, 123}}},
+		{28, []blockGroup{{13, 46}, {32, 47}}},
+		{30, []blockGroup{{48, 24}, {14, 25}}},
+		{30, []blockGroup{{42, 15}, {32, 16}}},
+	},
+	{ // 39
+		{30, []blockGroup{{20, 117}, {4, 118}}},
+		{28, []blockGroup{{40, 47}, {7, 48}}},
+		{30, []blockGroup{{43, 24}, {22, 25}}},
+		{30, []blockGroup{{10, 15}, {67, 16}}},
+	},
+	{ // 40
+		{30, []blockGroup{{19, 118}, {6, 119}}},
+		{28, []blockGroup{{18, 47}, {31, 48}}},
+		{30, []blockGroup{{34, 24}, {34, 25}}},
+		{30, []blockGroup{{20, 15}, {61, 16}}},
+	},
+}
+
+// alignmentCenters lists the alignment pattern coordinates per version.
+var alignmentCenters = [41][]int{
+	{},
+	{},
+	{6, 18},
+	{6, 22},
+	{6, 26},
+	{6, 30},
+	{6, 34},
+	{6, 22, 38},
+	{6, 24, 42},
+	{6, 26, 46},
+	{6, 28, 50},
+	{6, 30, 54},
+	{6, 32, 58},
+	{6, 34, 62},
+	{6, 26, 46, 66},
+	{6, 26, 48, 70},
+	{6, 26, 50, 74},
+	{6, 30, 54, 78},
+	{6, 30, 56, 82},
+	{6, 30, 58, 86},
+	{6, 34, 62, 90},
+	{6, 28, 50, 72, 94},
+	{6, 26, 50, 74, 98},
+	{6, 30, 54, 78, 102},
+	{6, 28, 54, 80, 106},
+	{6, 32, 58, 84, 110},
+	{6, 30, 58, 86, 114},
+	{6, 34, 62, 90, 118},
+	{6, 26, 50, 74, 98, 122},
+	{6, 30, 54, 78, 102, 126},
+	{6, 26, 52, 78, 104, 130},
+	{6, 30, 56, 82, 108, 134},
+	{6, 34, 60, 86, 112, 138},
+	{6, 30, 58, 86, 114, 142},
+	{6, 34, 62, 90, 118, 146},
+	{6, 30, 54, 78, 102, 126, 150},
+	{6, 24, 50, 76, 102, 128, 154},
+	{6, 28, 54, 80, 106, 132, 158},
+	{6, 32, 58, 84, 110, 136, 162},
+	{6, 26, 54, 82, 110, 138, 166},
+	{6, 30, 58, 86, 114, 142, 170},
+}
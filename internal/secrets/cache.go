@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"errors"
+	"sync"
+)
+
+// CachedStore remembers what it read from or wrote to another store, so
+// frequent reads do not reach the keyring each time. Changes made to the
+// underlying store by other processes are not seen.
+type CachedStore struct {
+	store  Store
+	mutex  sync.Mutex
+	values map[string]string
+	// missing holds keys known to have no secret
+	missing map[string]bool
+}
+
+func NewCachedStore(store Store) *CachedStore {
+	return &CachedStore{
+		store:   store,
+		values:  map[string]string{},
+		missing: map[string]bool{},
+	}
+}
+
+func (s *CachedStore) Get(key string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if value, ok := s.values[key]; ok {
+		return value, nil
+	}
+	if s.missing[key] {
+		return "", ErrNotFound
+	}
+
+	value, err := s.store.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		s.missing[key] = true
+	}
+	if err != nil {
+		return "", err
+	}
+	s.values[key] = value
+	return value, nil
+}
+
+// Set skips the underlying store when value is already stored.
+func (s *CachedStore) Set(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cached, ok := s.values[key]; ok && cached == value {
+		return nil
+	}
+
+	delete(s.values, key)
+	delete(s.missing, key)
+	err := s.store.Set(key, value)
+	if err != nil {
+		return err
+	}
+	s.values[key] = value
+	return nil
+}
+
+func (s *CachedStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.missing[key] {
+		return nil
+	}
+
+	delete(s.values, key)
+	err := s.store.Delete(key)
+	if err != nil {
+		return err
+	}
+	s.missing[key] = true
+	return nil
+}
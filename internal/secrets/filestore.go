@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const secretsFile = "secrets.enc"
+
+// FileStore keeps secrets AES-GCM encrypted with a key bound to this machine
+// and user. It protects against the file leaking on its own, not against
+// someone who can already act as the user on this machine.
+type FileStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{path: filepath.Join(dir, secretsFile)}
+}
+
+func (s *FileStore) Get(key string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *FileStore) Set(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	values[key] = value
+	return s.save(values)
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return s.save(values)
+}
+
+func (s *FileStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	gcm, err := newGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secrets file is corrupted")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file: %w", err)
+	}
+
+	err = json.Unmarshal(plaintext, &values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	return values, nil
+}
+
+func (s *FileStore) save(values map[string]string) error {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	gcm, err := newGCM()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	data := gcm.Seal(nonce, nonce, plaintext, nil)
+	err = os.WriteFile(s.path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return os.Chmod(s.path, 0600)
+}
+
+func newGCM() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(machineKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func machineKey() []byte {
+	id := ""
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			id = string(data)
+			break
+		}
+	}
+	if id == "" {
+		id, _ = os.Hostname()
+	}
+
+	sum := sha256.Sum256([]byte(service + "\x00" + id + "\x00" + strconv.Itoa(os.Getuid())))
+	return sum[:]
+}
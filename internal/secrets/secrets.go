@@ -0,0 +1,15 @@
+// Package secrets keeps sensitive values such as subscription URLs out of
+// plain text config files.
+package secrets
+
+import "errors"
+
+const service = "go-sing"
+
+var ErrNotFound = errors.New("secret not found")
+
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringStore keeps secrets in the Secret Service keyring through
+// secret-tool and falls back to an encrypted file when no keyring is reachable.
+type keyringStore struct {
+	fallback *FileStore
+}
+
+// NewStore returns the keyring store, using dir for the encrypted fallback file.
+func NewStore(dir string) Store {
+	return &keyringStore{fallback: NewFileStore(dir)}
+}
+
+func (s *keyringStore) available() bool {
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (s *keyringStore) Get(key string) (string, error) {
+	if s.available() {
+		var stdout bytes.Buffer
+		cmd := exec.Command("secret-tool", "lookup", "service", service, "key", key)
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err == nil && stdout.Len() > 0 {
+			return strings.TrimSuffix(stdout.String(), "\n"), nil
+		}
+	}
+
+	return s.fallback.Get(key)
+}
+
+func (s *keyringStore) Set(key, value string) error {
+	if s.available() {
+		cmd := exec.Command("secret-tool", "store", "--label", fmt.Sprintf("%s %s", service, key), "service", service, "key", key)
+		cmd.Stdin = strings.NewReader(value)
+		if err := cmd.Run(); err == nil {
+			return s.fallback.Delete(key)
+		}
+	}
+
+	return s.fallback.Set(key, value)
+}
+
+func (s *keyringStore) Delete(key string) error {
+	if s.available() {
+		exec.Command("secret-tool", "clear", "service", service, "key", key).Run()
+	}
+
+	return s.fallback.Delete(key)
+}
@@ -0,0 +1,8 @@
+//go:build !windows && !linux
+
+package secrets
+
+// NewStore returns the encrypted file store kept in dir.
+func NewStore(dir string) Store {
+	return NewFileStore(dir)
+}
@@ -0,0 +1,121 @@
+package secrets
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	ERROR_NOT_FOUND            = syscall.Errno(1168)
+	CRED_TYPE_GENERIC          = 1
+	CRED_PERSIST_LOCAL_MACHINE = 2
+	CRED_MAX_CREDENTIAL_BLOB   = 5 * 512
+)
+
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialStore keeps secrets in the Windows Credential Manager.
+type credentialStore struct{}
+
+// NewStore returns the Windows Credential Manager store. dir is unused on Windows.
+func NewStore(dir string) Store {
+	return credentialStore{}
+}
+
+func targetName(key string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + key)
+}
+
+func (credentialStore) Get(key string) (string, error) {
+	target, err := targetName(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ret, _, callErr := procCredReadW.Call(
+		uintptr(unsafe.Pointer(target)),
+		CRED_TYPE_GENERIC,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	)
+	if ret == 0 {
+		if callErr == ERROR_NOT_FOUND {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("CredRead failed: %w", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return string(blob), nil
+}
+
+func (credentialStore) Set(key, value string) error {
+	if len(value) > CRED_MAX_CREDENTIAL_BLOB {
+		return fmt.Errorf("secret is too long for the credential store")
+	}
+
+	target, err := targetName(key)
+	if err != nil {
+		return err
+	}
+
+	userName, err := syscall.UTF16PtrFromString(service)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(value)
+	cred := credential{
+		Type:               CRED_TYPE_GENERIC,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            CRED_PERSIST_LOCAL_MACHINE,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("CredWrite failed: %w", callErr)
+	}
+	return nil
+}
+
+func (credentialStore) Delete(key string) error {
+	target, err := targetName(key)
+	if err != nil {
+		return err
+	}
+
+	ret, _, callErr := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), CRED_TYPE_GENERIC, 0)
+	if ret == 0 && callErr != ERROR_NOT_FOUND {
+		return fmt.Errorf("CredDelete failed: %w", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+// Package urlscheme registers go-sing as the handler for subscription import
+// links, so clicking one in a browser hands it to go-sing.
+package urlscheme
+
+const appName = "go-sing"
+
+// Schemes are the URI schemes go-sing can import subscriptions from.
+var Schemes = []string{"sing-box", "clash"}
@@ -0,0 +1,92 @@
+package urlscheme
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const desktopFile = appName + "-links.desktop"
+
+func entryPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "applications", desktopFile), nil
+}
+
+func mimeTypes() []string {
+	types := make([]string, 0, len(Schemes))
+	for _, scheme := range Schemes {
+		types = append(types, "x-scheme-handler/"+scheme)
+	}
+	return types
+}
+
+// Enable writes a desktop entry for Schemes and makes it the default handler.
+func Enable(execPath string) error {
+	path, err := entryPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create applications directory: %w", err)
+	}
+
+	entry := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=Go Sing VPN Client
+Exec="%s" %%u
+Terminal=false
+NoDisplay=true
+MimeType=%s;
+`, execPath, strings.Join(mimeTypes(), ";"))
+
+	err = os.WriteFile(path, []byte(entry), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write desktop entry: %w", err)
+	}
+
+	exec.Command("update-desktop-database", filepath.Dir(path)).Run()
+
+	args := append([]string{"default", desktopFile}, mimeTypes()...)
+	output, err := exec.Command("xdg-mime", args...).CombinedOutput()
+	if err != nil && !errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("failed to set default link handler: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func Disable() error {
+	path, err := entryPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove desktop entry: %w", err)
+	}
+
+	exec.Command("update-desktop-database", filepath.Dir(path)).Run()
+	return nil
+}
+
+func IsEnabled() bool {
+	path, err := entryPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
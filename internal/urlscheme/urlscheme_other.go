@@ -0,0 +1,19 @@
+//go:build !windows && !linux
+
+package urlscheme
+
+import "errors"
+
+var errUnsupported = errors.New("opening import links is not supported on this platform")
+
+func Enable(execPath string) error {
+	return errUnsupported
+}
+
+func Disable() error {
+	return nil
+}
+
+func IsEnabled() bool {
+	return false
+}
@@ -0,0 +1,69 @@
+package urlscheme
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+const classesKey = `HKCU\Software\Classes\`
+
+func reg(args ...string) ([]byte, error) {
+	cmd := exec.Command("reg", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow: true,
+	}
+	return cmd.CombinedOutput()
+}
+
+// Enable registers execPath as the current user's handler for Schemes.
+func Enable(execPath string) error {
+	command := fmt.Sprintf(`"%s" "%%1"`, execPath)
+
+	for _, scheme := range Schemes {
+		key := classesKey + scheme
+		steps := [][]string{
+			{"add", key, "/ve", "/d", "URL:" + scheme + " link", "/f"},
+			{"add", key, "/v", "URL Protocol", "/d", "", "/f"},
+			{"add", key + `\shell\open\command`, "/ve", "/d", command, "/f"},
+		}
+		for _, args := range steps {
+			output, err := reg(args...)
+			if err != nil {
+				return fmt.Errorf("failed to register %s:// links: %w: %s", scheme, err, strings.TrimSpace(string(output)))
+			}
+		}
+	}
+	return nil
+}
+
+// Disable removes the registrations that point at go-sing, leaving handlers
+// installed by other clients alone.
+func Disable() error {
+	for _, scheme := range Schemes {
+		if !isOurs(scheme) {
+			continue
+		}
+
+		output, err := reg("delete", classesKey+scheme, "/f")
+		if err != nil {
+			return fmt.Errorf("failed to unregister %s:// links: %w: %s", scheme, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+func IsEnabled() bool {
+	for _, scheme := range Schemes {
+		if !isOurs(scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+func isOurs(scheme string) bool {
+	output, err := reg("query", classesKey+scheme+`\shell\open\command`, "/ve")
+	return err == nil && strings.Contains(strings.ToLower(string(output)), appName)
+}
@@ -1,48 +1,76 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"go-sing/cli"
 	"go-sing/config"
+	"go-sing/internal/ipc"
 	"go-sing/ui"
+	"go-sing/updater"
 	"go-sing/vpn"
 	"log"
-	"net"
 	"os"
 )
 
 func main() {
-	if !checkSingleInstance() {
-		log.Println("Another instance is already running")
-		os.Exit(0)
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:]))
 	}
 
-	configFetcher := config.NewFetcher()
-
-	app := ui.NewAppWithoutController(configFetcher)
-
-	vpnController := vpn.NewController(app)
+	minimized := flag.Bool("minimized", false, "start hidden in the system tray")
+	flag.Parse()
 
-	app.SetVPNController(vpnController)
-
-	app.Run()
-}
+	importLink := ""
+	if flag.NArg() > 0 && config.IsImportLink(flag.Arg(0)) {
+		importLink = flag.Arg(0)
+	}
 
-func checkSingleInstance() bool {
-	listener, err := net.Listen("tcp", "127.0.0.1:29582")
+	restarted, err := updater.ApplyPendingUpdate()
 	if err != nil {
+		log.Println("Could not apply pending update:", err)
+	}
+	if restarted {
+		os.Exit(0)
+	}
 
-		return false
+	paths, err := config.ResolvePaths()
+	if err != nil {
+		log.Println("Could not prepare data directory:", err)
+		os.Exit(1)
 	}
 
-	go func() {
-		defer listener.Close()
-		for {
-			conn, err := listener.Accept()
-			if err != nil {
-				break
-			}
-			conn.Close()
+	ipcServer, err := ipc.Listen(paths)
+	if errors.Is(err, ipc.ErrAlreadyRunning) {
+		log.Println("Another instance is already running, bringing it to the front")
+		if importLink != "" {
+			err = ipc.Call(paths, "import", []string{importLink}, nil)
+		} else {
+			err = ipc.Call(paths, "show", nil, nil)
 		}
-	}()
+		if err != nil {
+			log.Println("Could not reach the running instance:", err)
+		}
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Println("Could not start IPC server:", err)
+	}
+
+	configFetcher := config.NewFetcher(paths)
 
-	return true
+	app := ui.NewAppWithoutController(configFetcher)
+
+	vpnController := vpn.NewController(app, configFetcher)
+	vpnController.OnTransition = app.HandleTransition
+	vpnController.StartMonitoring()
+
+	app.SetVPNController(vpnController)
+	app.SetStartMinimized(*minimized)
+	app.SetImportLink(importLink)
+	if ipcServer != nil {
+		app.SetIPCServer(ipcServer)
+	}
+
+	app.Run()
 }
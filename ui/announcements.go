@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"go-sing/config"
+	"net/url"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+func (a *App) showAnnouncement(announcement config.Announcement) {
+	fyne.Do(func() {
+		title := "Announcement"
+		switch announcement.Severity {
+		case config.SeverityWarning:
+			title = "Warning"
+		case config.SeverityCritical:
+			title = "Important"
+		}
+
+		text := widget.NewLabel(announcement.Text)
+		text.Wrapping = fyne.TextWrapWord
+		content := container.NewVBox(text)
+
+		if announcement.Link != "" {
+			if link, err := url.Parse(announcement.Link); err == nil {
+				content.Add(widget.NewHyperlink(announcement.Link, link))
+			}
+		}
+
+		d := dialog.NewCustom(title, "OK", content, a.window)
+		d.Resize(fyne.NewSize(420, 0))
+		d.Show()
+	})
+}
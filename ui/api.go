@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/api"
+	"go-sing/config"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// updateAPIServer starts, stops or moves the local REST API to match settings.
+func (a *App) updateAPIServer(settings config.Settings) {
+	if a.apiServer != nil && (!settings.APIEnabled || settings.APIPort != a.apiPort) {
+		a.stopAPIServer()
+	}
+	if !settings.APIEnabled || a.apiServer != nil || a.vpnController == nil {
+		return
+	}
+
+	token, err := a.configFetcher.APIToken()
+	if err != nil {
+		a.Log("Error starting local API: " + err.Error())
+		return
+	}
+
+	server := api.NewServer(remoteControl{a: a}, token)
+	err = server.Start(settings.APIPort)
+	if err != nil {
+		a.Log("Error starting local API: " + err.Error())
+		return
+	}
+
+	a.apiServer = server
+	a.apiPort = settings.APIPort
+	a.Log(fmt.Sprintf("Local API listening on http://127.0.0.1:%d/api/v1", settings.APIPort))
+}
+
+func (a *App) stopAPIServer() {
+	if a.apiServer == nil {
+		return
+	}
+	a.apiServer.Stop()
+	a.apiServer = nil
+	a.Log("Local API stopped")
+}
+
+// newAPITokenField shows the API token with buttons to copy or replace it.
+func (a *App) newAPITokenField() fyne.CanvasObject {
+	token, err := a.configFetcher.APIToken()
+	if err != nil {
+		return widget.NewLabel("Unavailable: " + err.Error())
+	}
+
+	entry := widget.NewPasswordEntry()
+	entry.SetText(token)
+	entry.Disable()
+
+	copyBtn := widget.NewButton("Copy", func() {
+		a.window.Clipboard().SetContent(token)
+		a.Log("API token copied to clipboard")
+	})
+
+	resetBtn := widget.NewButton("Reset", func() {
+		newToken, err := a.configFetcher.ResetAPIToken()
+		if err != nil {
+			a.Log("Error resetting API token: " + err.Error())
+			return
+		}
+		token = newToken
+		entry.SetText(token)
+		a.Log("API token reset")
+
+		if a.apiServer != nil {
+			a.stopAPIServer()
+			a.updateAPIServer(a.currentSettings())
+		}
+	})
+
+	return container.NewBorder(nil, nil, nil, container.NewHBox(copyBtn, resetBtn), entry)
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"go-sing/api"
 	"go-sing/config"
+	"go-sing/internal/ipc"
+	"go-sing/internal/logrotate"
+	"go-sing/updater"
+	"go-sing/vpn"
 	"os"
 	"strings"
 	"sync"
@@ -13,7 +18,6 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -21,8 +25,17 @@ type ConfigFetcher interface {
 	FetchConfig(url string) (string, error)
 	GetConfigPath() (string, error)
 	LoadAppConfig() (*config.AppConfig, error)
-	SaveAppConfig(appConfig *config.AppConfig) error
+	UpdateAppConfig(update func(appConfig *config.AppConfig) error) error
 	EnsureAppConfigExists() error
+	SetChannel(channel string) error
+	Profiles() ([]config.Profile, string, error)
+	AddProfile(name, url string) (string, error)
+	SwitchProfile(name string) (*config.Profile, error)
+	RemoveProfile(name string) error
+	Paths() *config.Paths
+	APIToken() (string, error)
+	ResetAPIToken() (string, error)
+	SubscriptionInfo() *config.SubscriptionInfo
 }
 
 type VPNController interface {
@@ -30,6 +43,16 @@ type VPNController interface {
 	StopVPN() error
 	IsRunning() bool
 	IsSingBoxAvailable() bool
+	CurrentChannel() string
+	AvailableChannels() []string
+	ClientUpdateRequired() bool
+	ApplySettings(settings config.Settings)
+	RestartVPN() error
+	Status() vpn.Status
+	Outbounds() ([]vpn.OutboundGroup, error)
+	Traffic() (*vpn.Traffic, error)
+	ExportDiagnostics(path string, hideServers bool) error
+	SelectOutbound(group, outbound string) error
 }
 
 type VPNControllerWithStop interface {
@@ -44,19 +67,52 @@ type App struct {
 	configText    *widget.RichText
 	startBtn      *widget.Button
 	stopBtn       *widget.Button
-	logsText      *widget.RichText
-	logBuffer     string
+	channelSelect *widget.Select
+	profileSelect *widget.Select
+	profile       string
+	profiles      []string
+	channelChange string
+	redactCheck   *widget.Check
+	hideServers   *widget.Check
+	logStore      *LogStore
+	logView       *logView
+	logLevel      *widget.Select
+	logComponent  *widget.Entry
+	logConnection *widget.Entry
+	logSearch     *widget.Entry
+	logFollow     *widget.Check
+	logDebug      *widget.Check
+	prevLogLevel  string
 	configBuffer  string
 	configFetcher ConfigFetcher
 	vpnController VPNControllerWithStop
 	configWatcher *config.Watcher
 	logWatcher    *LogWatcher
+	updater       *updater.Updater
 	ctx           context.Context
 	cancel        context.CancelFunc
 	trayStartItem *fyne.MenuItem
 	trayStopItem  *fyne.MenuItem
-	appLogs       chan string
+	trayState     string
+	trayMenu      trayMenuState
+	trayOutbounds []vpn.OutboundGroup
+	trayFetchedAt time.Time
+	trayMutex     sync.Mutex
+	appLogs       *LogQueue
+	appLogFile    *logrotate.File
 	once          sync.Once
+	settings      config.Settings
+	settingsMutex sync.RWMutex
+	minimized     bool
+	ipcServer     *ipc.Server
+	apiServer     *api.Server
+	apiPort       int
+	importLink    string
+	uiReady       chan struct{}
+	notifyMutex   sync.Mutex
+	lastNotified  map[string]time.Time
+	queuedNotices map[string]*fyne.Notification
+	quotaWarning  string
 }
 
 func NewAppWithoutController(configFetcher ConfigFetcher) *App {
@@ -65,7 +121,12 @@ func NewAppWithoutController(configFetcher ConfigFetcher) *App {
 		configFetcher: configFetcher,
 		ctx:           ctx,
 		cancel:        cancel,
-		appLogs:       make(chan string, 100),
+		appLogs:       NewLogQueue(maxLogEntries),
+		logStore:      NewLogStore(maxLogEntries),
+		uiReady:       make(chan struct{}),
+		lastNotified:  map[string]time.Time{},
+		queuedNotices: map[string]*fyne.Notification{},
+		settings:      config.DefaultSettings(),
 	}
 }
 
@@ -73,6 +134,23 @@ func (a *App) SetVPNController(vpnController VPNControllerWithStop) {
 	a.vpnController = vpnController
 }
 
+// SetIPCServer lets other go-sing processes control this instance once the
+// window is set up.
+func (a *App) SetIPCServer(server *ipc.Server) {
+	a.ipcServer = server
+}
+
+// SetImportLink asks to import the subscription from a deep link once the
+// window is up.
+func (a *App) SetImportLink(link string) {
+	a.importLink = link
+}
+
+// SetStartMinimized keeps the window hidden in the system tray on launch.
+func (a *App) SetStartMinimized(minimized bool) {
+	a.minimized = minimized
+}
+
 func (a *App) Run() {
 	a.once.Do(func() {
 		a.setupUI()
@@ -82,28 +160,46 @@ func (a *App) Run() {
 
 func (a *App) setupUI() {
 	a.fyneApp = app.New()
-	a.fyneApp.SetIcon(nil)
 
-	a.window = a.fyneApp.NewWindow("Go Sing VPN Client (v1.1)")
+	a.window = a.fyneApp.NewWindow(fmt.Sprintf("Go Sing VPN Client (v%s)", config.ClientVersion))
 	a.window.Resize(fyne.NewSize(800, 600))
 
 	a.createComponents()
 	layout := a.createLayout()
 	a.window.SetContent(layout)
 	a.setupSystemTray()
+	close(a.uiReady)
 
 	a.window.SetCloseIntercept(func() {
 		a.window.Hide()
 	})
 
+	if a.ipcServer != nil {
+		a.ipcServer.Serve(a.handleCommand)
+	}
+
 	a.configWatcher = config.NewConfigWatcher(a.configFetcher.(*config.Fetcher), a)
+	a.configWatcher.OnConfigChanged = a.handleConfigChanged
 	a.configWatcher.Start()
 
+	a.openAppLogFile()
 	a.startLogWatcher()
 
+	a.updater = updater.NewUpdater(a.configFetcher.(*config.Fetcher), a)
+	a.updater.OnUpdateAvailable = a.showUpdateDialog
+	a.updater.OnAnnouncement = a.showAnnouncement
+	a.updater.Start()
+
 	go a.startPeriodicUpdater()
 
-	a.window.Show()
+	a.loadSettings()
+	settings := a.currentSettings()
+
+	if a.minimized || settings.StartMinimized {
+		a.Log("Started minimized to the system tray")
+	} else {
+		a.window.Show()
+	}
 
 	err := a.configFetcher.EnsureAppConfigExists()
 	if err != nil {
@@ -113,10 +209,19 @@ func (a *App) setupUI() {
 	a.loadAppConfig()
 	a.loadExistingSingBoxConfig()
 
-	if a.logBuffer != "" && a.logsText != nil {
-		a.logsText.ParseMarkdown("```\n" + a.logBuffer + "\n```")
+	if settings.AutoConnect {
+		go a.autoConnect()
 	}
 
+	if a.importLink != "" {
+		link := a.importLink
+		go fyne.Do(func() {
+			a.promptImport(link)
+		})
+	}
+
+	a.logView.Update()
+
 }
 
 func (a *App) createComponents() {
@@ -127,29 +232,49 @@ func (a *App) createComponents() {
 	a.stopBtn = widget.NewButton("Stop", a.handleStopVPN)
 	a.stopBtn.Disable()
 
+	a.channelSelect = widget.NewSelect(nil, a.handleChannelChanged)
+	a.channelSelect.PlaceHolder = "Channel"
+
+	a.profileSelect = widget.NewSelect(nil, a.handleProfileChanged)
+	a.profileSelect.PlaceHolder = "No profiles"
+
+	a.redactCheck = widget.NewCheck("Redact", func(checked bool) {
+		if checked {
+			a.hideServers.Enable()
+		} else {
+			a.hideServers.Disable()
+		}
+	})
+	a.hideServers = widget.NewCheck("Hide servers", nil)
+	a.redactCheck.SetChecked(true)
+
 	a.configText = widget.NewRichText()
 	a.configText.Resize(fyne.NewSize(380, 300))
 	a.configText.Wrapping = fyne.TextWrapWord
 
-	a.logsText = widget.NewRichText()
-	a.logsText.Resize(fyne.NewSize(380, 300))
-	a.logsText.Wrapping = fyne.TextWrapWord
+	a.logView = newLogView(a.logStore)
+	a.createLogFilters()
 	a.Log("Application started")
 }
 
 func (a *App) createLayout() *container.Split {
-	urlContainer := container.NewBorder(nil, nil, nil, widget.NewButton("Update Config", a.handleUpdateConfig), a.urlEntry)
+	urlButtons := container.NewHBox(widget.NewButton("Update Config", a.handleUpdateConfig), a.newQRButton())
+	urlContainer := container.NewBorder(nil, nil, nil, urlButtons, a.urlEntry)
+	profileContainer := container.NewBorder(nil, nil, widget.NewLabel("Profile:"), widget.NewButton("Remove Profile", a.handleRemoveProfile), a.profileSelect)
 	quitBtn := widget.NewButton("Quit", a.handleQuit)
-	buttonContainer := container.NewHBox(a.startBtn, a.stopBtn)
+	settingsBtn := widget.NewButton("Settings", a.showSettingsDialog)
+	buttonContainer := container.NewHBox(a.startBtn, a.stopBtn, widget.NewLabel("Channel:"), a.channelSelect)
 	configScroll := container.NewScroll(a.configText)
 	configScroll.SetMinSize(fyne.NewSize(380, 300))
 
-	configHeader := container.NewBorder(nil, nil, widget.NewLabel("Configuration:"), widget.NewButton("Copy Config", a.handleCopyConfig), nil)
+	copyControls := container.NewHBox(a.redactCheck, a.hideServers, widget.NewButton("Copy Config", a.handleCopyConfig))
+	configHeader := container.NewBorder(nil, nil, widget.NewLabel("Configuration:"), copyControls, nil)
 
 	topSection := container.NewVBox(
 		widget.NewLabel("Subscription URL:"),
 		urlContainer,
-		quitBtn,
+		profileContainer,
+		container.NewGridWithColumns(2, settingsBtn, quitBtn),
 		widget.NewSeparator(),
 		buttonContainer,
 		widget.NewSeparator(),
@@ -158,46 +283,30 @@ func (a *App) createLayout() *container.Split {
 
 	leftSide := container.NewBorder(topSection, nil, nil, nil, configScroll)
 
-	logsScroll := container.NewScroll(a.logsText)
-	logsScroll.SetMinSize(fyne.NewSize(380, 300))
-
-	logsHeader := container.NewBorder(nil, nil, widget.NewLabel("Logs:"), widget.NewButton("Copy Logs", a.handleCopyLogs), nil)
-	rightSide := container.NewBorder(logsHeader, nil, nil, nil, logsScroll)
+	logControls := container.NewHBox(a.logFollow, a.logDebug, widget.NewButton("Copy Logs", a.handleCopyLogs), widget.NewButton("Export Diagnostics", a.handleExportDiagnostics))
+	logsHeader := container.NewBorder(nil, nil, widget.NewLabel("Logs:"), logControls, nil)
+	logFilters := container.NewGridWithColumns(4, a.logLevel, a.logComponent, a.logConnection, a.logSearch)
+	rightSide := container.NewBorder(container.NewVBox(logsHeader, logFilters), nil, nil, nil, a.logView.list)
 
 	split := container.NewHSplit(leftSide, rightSide)
 	split.Offset = 0.5
 	return split
 }
 
-func (a *App) setupSystemTray() {
-	if desk, ok := a.fyneApp.(desktop.App); ok {
-		a.trayStartItem = fyne.NewMenuItem("Start VPN", a.handleStartVPN)
-		a.trayStopItem = fyne.NewMenuItem("Stop VPN", a.handleStopVPN)
-		a.trayStopItem.Disabled = true
-
-		menu := fyne.NewMenu("Sing-Box VPN",
-			fyne.NewMenuItem("Show", func() {
-				a.window.Show()
-			}),
-			a.trayStartItem,
-			a.trayStopItem,
-			fyne.NewMenuItemSeparator(),
-			fyne.NewMenuItem("Quit", func() {
-				a.fyneApp.Quit()
-			}),
-		)
-		desk.SetSystemTrayMenu(menu)
-	}
+func (a *App) handleUpdateConfig() {
+	a.updateSubscription("", a.urlEntry.Text)
 }
 
-func (a *App) handleUpdateConfig() {
-	url := strings.TrimSpace(a.urlEntry.Text)
+// updateSubscription fetches url and saves it as the profile in use, called
+// name unless the URL is saved already.
+func (a *App) updateSubscription(name, url string) {
+	url = strings.TrimSpace(url)
 	if url == "" {
 		a.Log("Error: Please enter a subscription URL")
 		return
 	}
 
-	a.Log("Fetching configuration from: " + url)
+	a.Log("Fetching configuration from: " + config.RedactURL(url))
 
 	go func() {
 		_, err := a.configFetcher.FetchConfig(url)
@@ -206,15 +315,13 @@ func (a *App) handleUpdateConfig() {
 			return
 		}
 
-		appConfig := &config.AppConfig{
-			SubscriptionURL: url,
-		}
-		err = a.configFetcher.SaveAppConfig(appConfig)
+		_, err = a.configFetcher.AddProfile(name, url)
 		if err != nil {
 			a.Log("Warning: Could not save subscription URL: " + err.Error())
 		}
 
 		a.configWatcher.UpdateURL(url)
+		a.refreshProfiles()
 
 		a.loadExistingSingBoxConfig()
 		a.Log("Configuration updated successfully")
@@ -229,6 +336,7 @@ func (a *App) loadAppConfig() {
 	if appConfig.SubscriptionURL != "" {
 		a.urlEntry.SetText(appConfig.SubscriptionURL)
 		a.configWatcher.UpdateURL(appConfig.SubscriptionURL)
+		a.refreshProfiles()
 		go func() {
 			_, err := a.configFetcher.FetchConfig(appConfig.SubscriptionURL)
 			if err != nil {
@@ -315,6 +423,42 @@ func (a *App) handleStopVPN() {
 	}()
 }
 
+func (a *App) handleChannelChanged(channel string) {
+	if channel == "" || a.vpnController == nil || channel == a.vpnController.CurrentChannel() {
+		return
+	}
+
+	err := a.configFetcher.SetChannel(channel)
+	if err != nil {
+		a.Log("Error switching channel: " + err.Error())
+		return
+	}
+
+	a.channelChange = channel
+	a.Log("Switching sing-box to " + channel + " channel")
+}
+
+func (a *App) updateChannelSelect() {
+	if a.vpnController == nil {
+		return
+	}
+
+	channels := a.vpnController.AvailableChannels()
+	if strings.Join(channels, ",") != strings.Join(a.channelSelect.Options, ",") {
+		a.channelSelect.SetOptions(channels)
+	}
+
+	current := a.vpnController.CurrentChannel()
+	if a.channelChange != "" && a.channelChange != current {
+		return
+	}
+	a.channelChange = ""
+
+	if current != "" && a.channelSelect.Selected != current {
+		a.channelSelect.SetSelected(current)
+	}
+}
+
 func (a *App) handleQuit() {
 	a.Log("Shutting down application...")
 	a.handleStopVPN()
@@ -347,8 +491,16 @@ func (a *App) updateButtonStates() {
 	singBoxAvailable := a.vpnController.IsSingBoxAvailable()
 	isRunning := a.vpnController.IsRunning()
 	configExists := a.configExists()
+	updateRequired := a.vpnController.ClientUpdateRequired()
 
-	if !singBoxAvailable {
+	if updateRequired && !isRunning {
+		a.startBtn.Disable()
+		a.stopBtn.Disable()
+		if a.startBtn.Text != "Update Required" {
+			a.startBtn.SetText("Update Required")
+		}
+		a.updateTrayItems(false, false)
+	} else if !singBoxAvailable {
 		a.startBtn.Disable()
 		a.stopBtn.Disable()
 		if a.startBtn.Text != "Downloading..." {
@@ -379,63 +531,6 @@ func (a *App) updateButtonStates() {
 	}
 }
 
-func (a *App) updateTrayItems(startEnabled, stopEnabled bool) {
-	if a.trayStartItem == nil || a.trayStopItem == nil {
-		return
-	}
-
-	oldStartDisabled := a.trayStartItem.Disabled
-	oldStartLabel := a.trayStartItem.Label
-	oldStopDisabled := a.trayStopItem.Disabled
-
-	a.trayStartItem.Disabled = !startEnabled
-	a.trayStartItem.Label = a.getStartButtonLabel()
-
-	a.trayStopItem.Disabled = !stopEnabled
-
-	if a.trayStateChanged(oldStartDisabled, oldStartLabel, oldStopDisabled) {
-		a.refreshSystemTrayMenu()
-	}
-}
-
-func (a *App) getStartButtonLabel() string {
-	if a.vpnController == nil {
-		return "Start VPN (Initializing...)"
-	}
-	if !a.vpnController.IsSingBoxAvailable() {
-		return "Start VPN (Downloading...)"
-	}
-	if !a.configExists() {
-		return "Start VPN (No Config)"
-	}
-	return "Start VPN"
-}
-
-func (a *App) trayStateChanged(oldStartDisabled bool, oldStartLabel string, oldStopDisabled bool) bool {
-	return oldStartDisabled != a.trayStartItem.Disabled ||
-		oldStartLabel != a.trayStartItem.Label ||
-		oldStopDisabled != a.trayStopItem.Disabled
-}
-
-func (a *App) refreshSystemTrayMenu() {
-	desk, ok := a.fyneApp.(desktop.App)
-	if !ok {
-		return
-	}
-	menu := fyne.NewMenu("Sing-Box VPN",
-		fyne.NewMenuItem("Show", func() {
-			a.window.Show()
-		}),
-		a.trayStartItem,
-		a.trayStopItem,
-		fyne.NewMenuItemSeparator(),
-		fyne.NewMenuItem("Quit", func() {
-			a.fyneApp.Quit()
-		}),
-	)
-	desk.SetSystemTrayMenu(menu)
-}
-
 func (a *App) configExists() bool {
 	configPath, err := a.configFetcher.GetConfigPath()
 	if err != nil {
@@ -447,18 +542,28 @@ func (a *App) configExists() bool {
 }
 
 func (a *App) startPeriodicUpdater() {
-	logTicker := time.NewTicker(1 * time.Second)
+	logInterval := a.currentSettings().LogViewRefreshInterval()
+	logTicker := time.NewTicker(logInterval)
+	configTicker := time.NewTicker(1 * time.Second)
 	uiTicker := time.NewTicker(2 * time.Second)
 	defer logTicker.Stop()
+	defer configTicker.Stop()
 	defer uiTicker.Stop()
 
 	for {
 		select {
 		case <-logTicker.C:
 			a.refreshLogsUI()
+			if interval := a.currentSettings().LogViewRefreshInterval(); interval != logInterval {
+				logInterval = interval
+				logTicker.Reset(interval)
+			}
+		case <-configTicker.C:
 			a.loadExistingSingBoxConfig()
 		case <-uiTicker.C:
 			a.updateButtonStates()
+			a.updateChannelSelect()
+			a.checkSubscriptionQuota()
 		case <-a.ctx.Done():
 			return
 		}
@@ -467,11 +572,19 @@ func (a *App) startPeriodicUpdater() {
 
 func (a *App) Stop() {
 	a.cancel()
+	if a.ipcServer != nil {
+		a.ipcServer.Close()
+	}
+	a.stopAPIServer()
 	if a.vpnController != nil {
 		a.vpnController.Stop()
 	}
 	if a.configWatcher != nil {
 		a.configWatcher.Stop()
 	}
+	if a.updater != nil {
+		a.updater.Stop()
+	}
 	a.stopLogWatcher()
+	a.closeAppLogFile()
 }
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	autoConnectPollInterval  = 2 * time.Second
+	autoConnectRetryInterval = 30 * time.Second
+	maxAutoConnectAttempts   = 3
+)
+
+// autoConnect starts the VPN once sing-box and its config are ready,
+// re-fetching the config and retrying the start if the first attempt fails.
+func (a *App) autoConnect() {
+	ticker := time.NewTicker(autoConnectPollInterval)
+	defer ticker.Stop()
+
+	attempts := 0
+	lastAttempt := time.Now()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if a.vpnController == nil || a.vpnController.IsRunning() {
+			return
+		}
+
+		if !a.vpnController.IsSingBoxAvailable() || a.vpnController.ClientUpdateRequired() {
+			continue
+		}
+
+		if !a.configExists() {
+			if time.Since(lastAttempt) >= autoConnectRetryInterval {
+				lastAttempt = time.Now()
+				a.Log("Auto-connect: no sing-box config yet, fetching it again...")
+				a.refetchSubscription()
+			}
+			continue
+		}
+
+		if attempts > 0 && time.Since(lastAttempt) < autoConnectRetryInterval {
+			continue
+		}
+
+		attempts++
+		lastAttempt = time.Now()
+		a.Log(fmt.Sprintf("Auto-connect: starting VPN (attempt %d/%d)...", attempts, maxAutoConnectAttempts))
+
+		err := a.vpnController.StartVPN()
+		if err == nil {
+			a.Log("VPN connection established")
+			return
+		}
+
+		a.Log("Auto-connect: error starting VPN: " + err.Error())
+		if attempts >= maxAutoConnectAttempts {
+			a.Log("Auto-connect: giving up")
+			return
+		}
+	}
+}
+
+func (a *App) refetchSubscription() {
+	appConfig, err := a.configFetcher.LoadAppConfig()
+	if err != nil || appConfig.SubscriptionURL == "" {
+		return
+	}
+
+	_, err = a.configFetcher.FetchConfig(appConfig.SubscriptionURL)
+	if err != nil {
+		a.Log("Auto-connect: error fetching sing-box config: " + err.Error())
+		return
+	}
+	a.loadExistingSingBoxConfig()
+}
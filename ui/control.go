@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/api"
+	"go-sing/vpn"
+	"slices"
+
+	"fyne.io/fyne/v2"
+)
+
+// remoteControl carries out requests from other processes, over IPC or the
+// REST API, the same way the matching buttons do.
+type remoteControl struct {
+	a *App
+}
+
+func (r remoteControl) Status() vpn.Status {
+	return r.a.vpnController.Status()
+}
+
+func (r remoteControl) Start() error {
+	controller := r.a.vpnController
+	if controller.IsRunning() {
+		return nil
+	}
+	if !controller.IsSingBoxAvailable() {
+		return fmt.Errorf("%w: sing-box is not available yet", api.ErrUnavailable)
+	}
+
+	r.a.Log("Starting VPN connection (remote request)...")
+	err := controller.StartVPN()
+	if err != nil {
+		r.a.Log("Error starting VPN: " + err.Error())
+		return err
+	}
+	r.a.Log("VPN connection established")
+	return nil
+}
+
+func (r remoteControl) Stop() error {
+	controller := r.a.vpnController
+	if !controller.IsRunning() {
+		return nil
+	}
+
+	r.a.Log("Stopping VPN connection (remote request)...")
+	err := controller.StopVPN()
+	if err != nil {
+		r.a.Log("Error stopping VPN: " + err.Error())
+		return err
+	}
+	r.a.Log("VPN connection stopped")
+	return nil
+}
+
+func (r remoteControl) Profiles() (string, []string) {
+	profiles, current, err := r.a.configFetcher.Profiles()
+	if err != nil {
+		r.a.Log("Warning: Could not load profiles: " + err.Error())
+		return "", nil
+	}
+
+	names := []string{}
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return current, names
+}
+
+func (r remoteControl) SwitchProfile(name string) error {
+	_, available := r.Profiles()
+	if !slices.Contains(available, name) {
+		return fmt.Errorf("no profile called %q", name)
+	}
+
+	r.a.Log("Switching profile (remote request)...")
+	err := r.a.switchProfile(name)
+	if err != nil {
+		r.a.Log("Error switching profile: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r remoteControl) Channels() (string, []string) {
+	return r.a.vpnController.CurrentChannel(), r.a.vpnController.AvailableChannels()
+}
+
+func (r remoteControl) SwitchChannel(name string) error {
+	if !slices.Contains(r.a.vpnController.AvailableChannels(), name) {
+		return fmt.Errorf("channel %q is not offered by the delivery config", name)
+	}
+
+	err := r.a.configFetcher.SetChannel(name)
+	if err != nil {
+		return fmt.Errorf("failed to switch channel: %w", err)
+	}
+
+	fyne.Do(func() {
+		r.a.channelChange = name
+	})
+	r.a.Log("Switching sing-box to " + name + " channel")
+	return nil
+}
+
+func (r remoteControl) FetchConfig() error {
+	appConfig, err := r.a.configFetcher.LoadAppConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load app config: %w", err)
+	}
+	if appConfig.SubscriptionURL == "" {
+		return fmt.Errorf("%w: no subscription URL saved", api.ErrUnavailable)
+	}
+
+	_, err = r.a.configFetcher.FetchConfig(appConfig.SubscriptionURL)
+	if err != nil {
+		r.a.Log("Error fetching config: " + err.Error())
+		return fmt.Errorf("failed to fetch config: %w", err)
+	}
+
+	r.a.Log("Configuration updated successfully")
+	fyne.Do(r.a.loadExistingSingBoxConfig)
+	return nil
+}
+
+func (r remoteControl) Outbounds() ([]vpn.OutboundGroup, error) {
+	return r.a.vpnController.Outbounds()
+}
+
+func (r remoteControl) Traffic() (*vpn.Traffic, error) {
+	return r.a.vpnController.Traffic()
+}
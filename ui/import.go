@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/config"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// promptImport asks the user whether to switch to the subscription offered
+// by an import link. Links can come from any web page, so nothing is fetched
+// without confirmation.
+func (a *App) promptImport(link string) {
+	importLink, err := config.ParseImportLink(link)
+	if err != nil {
+		a.Log("Error importing link: " + err.Error())
+		return
+	}
+
+	a.confirmImport(importLink)
+}
+
+// confirmImport shows the subscription and switches to it if the user
+// agrees.
+func (a *App) confirmImport(importLink *config.ImportLink) {
+	a.window.Show()
+	a.window.RequestFocus()
+
+	message := fmt.Sprintf("Use the subscription %q from %s?\n\nIt is saved as a profile and used from now on.",
+		importLink.Name, config.RedactURL(importLink.URL))
+	dialog.ShowConfirm("Add subscription", message, func(confirmed bool) {
+		if !confirmed {
+			a.Log("Import of subscription " + importLink.Name + " cancelled")
+			return
+		}
+
+		a.Log("Importing subscription " + importLink.Name)
+		a.urlEntry.SetText(importLink.URL)
+		a.updateSubscription(importLink.Name, importLink.URL)
+	}, a.window)
+}
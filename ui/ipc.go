@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/config"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+)
+
+// handleCommand runs a command forwarded by another go-sing process, either a
+// second GUI launch or the CLI.
+func (a *App) handleCommand(command string, args []string) (any, error) {
+	control := remoteControl{a: a}
+
+	switch command {
+	case "show":
+		fyne.Do(func() {
+			a.window.Show()
+			a.window.RequestFocus()
+		})
+		return nil, nil
+	case "status":
+		return control.Status(), nil
+	case "start":
+		return nil, control.Start()
+	case "stop":
+		return nil, control.Stop()
+	case "import":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: import <link>")
+		}
+		_, err := config.ParseImportLink(args[0])
+		if err != nil {
+			return nil, err
+		}
+		fyne.Do(func() {
+			a.promptImport(args[0])
+		})
+		return nil, nil
+	case "diagnostics":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("usage: diagnostics <path> <hide-servers>")
+		}
+		hideServers, err := strconv.ParseBool(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return nil, a.vpnController.ExportDiagnostics(args[0], hideServers)
+	case "profiles":
+		current, available := control.Profiles()
+		return map[string]any{"current": current, "available": available}, nil
+	case "switch-profile":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: switch-profile <name>")
+		}
+		return nil, control.SwitchProfile(args[0])
+	case "switch-channel":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: switch-channel <name>")
+		}
+		return nil, control.SwitchChannel(args[0])
+	}
+
+	return nil, fmt.Errorf("unknown command %q", command)
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"regexp"
+	"time"
+)
+
+var logLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL", "PANIC"}
+
+// singBoxLogLine matches sing-box's log format, e.g.
+// "+0000 2025-01-02 15:04:05 INFO [3012437281 12ms] outbound/vless[proxy]: outbound connection to example.com:443".
+// The timestamp and connection id are optional.
+var singBoxLogLine = regexp.MustCompile(`^(?:([+-]\d{4} \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) )?` +
+	`(TRACE|DEBUG|INFO|WARN|ERROR|FATAL|PANIC) ` +
+	`(?:\[(\d+)(?: [^\]]*)?\] )?` +
+	`(?:([a-z][\w-]*(?:/[\w-]+)?)(?:\[[^\]]*\])?: )?`)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// parseSingBoxLine fills in the level, component and connection id of a
+// sing-box log line. Lines in an unknown format are kept as plain text.
+func parseSingBoxLine(line string) LogEntry {
+	entry := LogEntry{
+		Time:   time.Now(),
+		Source: LogSourceSingBox,
+		Text:   line,
+	}
+
+	match := singBoxLogLine.FindStringSubmatch(line)
+	if match == nil {
+		return entry
+	}
+
+	if match[1] != "" {
+		if t, err := time.Parse("-0700 2006-01-02 15:04:05", match[1]); err == nil {
+			entry.Time = t
+		}
+	}
+	entry.Level = match[2]
+	entry.ConnectionID = match[3]
+	entry.Component = match[4]
+	return entry
+}
+
+func logLevelRank(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	// Unparsed lines are shown along with INFO
+	return 2
+}
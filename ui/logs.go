@@ -3,49 +3,136 @@ package ui
 import (
 	"fmt"
 	"go-sing/config"
-	"os"
-	"path/filepath"
+	"go-sing/internal/logrotate"
+	"go-sing/vpn"
+	"strings"
 	"time"
-)
 
-const (
-	maxLogsPerSecInUI = 2
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
 )
 
 func (a *App) addAppLog(message string) {
-	timestamp := time.Now().Format("15:04:05")
-	logEntry := fmt.Sprintf("[%s] %s", timestamp, message)
-
-	a.appLogs <- logEntry
+	a.appLogs.Push(LogEntry{
+		Time:   time.Now(),
+		Source: LogSourceApp,
+		Level:  appLogLevel(message),
+		Text:   config.RedactSecrets(message),
+	})
 }
 
 func (a *App) Log(message string) {
 	a.addAppLog(message)
 }
 
+// newRedactor returns nil when the user asked for unredacted copies. It has
+// already seen the current config, so hidden servers are masked in logs too.
+func (a *App) newRedactor() *config.Redactor {
+	if !a.redactCheck.Checked {
+		return nil
+	}
+
+	redactor := config.NewRedactor(a.hideServers.Checked)
+	if a.configBuffer != "" {
+		redactor.RedactConfig([]byte(a.configBuffer))
+	}
+	return redactor
+}
+
 func (a *App) handleCopyConfig() {
 	if a.configBuffer == "" {
 		return
 	}
 
-	a.window.Clipboard().SetContent(a.configBuffer)
+	redactor := a.newRedactor()
+	if redactor == nil {
+		a.window.Clipboard().SetContent(a.configBuffer)
+		return
+	}
+
+	redacted, err := redactor.RedactConfig([]byte(a.configBuffer))
+	if err != nil {
+		a.Log("Error redacting config: " + err.Error())
+		return
+	}
+
+	a.window.Clipboard().SetContent(redacted)
 }
 
 func (a *App) handleCopyLogs() {
-	if a.logBuffer == "" {
+	logs := a.logStore.Text()
+	if logs == "" {
+		return
+	}
+
+	redactor := a.newRedactor()
+	if redactor == nil {
+		a.window.Clipboard().SetContent(logs)
 		return
 	}
 
-	a.window.Clipboard().SetContent(a.logBuffer)
+	a.window.Clipboard().SetContent(redactor.RedactText(logs))
 }
 
-func (a *App) startLogWatcher() {
-	appDir, _ := os.Executable()
-	appDir = filepath.Dir(appDir)
-	dataDir := filepath.Join(appDir, config.GoSingDataDir)
-	logPath := filepath.Join(dataDir, config.SingBoxLogDir, config.SingBoxLogFile)
+const allLogLevels = "All levels"
+
+func (a *App) createLogFilters() {
+	a.logLevel = widget.NewSelect([]string{allLogLevels, "DEBUG", "INFO", "WARN", "ERROR"}, nil)
+	a.logLevel.SetSelected(allLogLevels)
+	a.logLevel.OnChanged = func(string) {
+		a.applyLogFilter()
+	}
+
+	a.logComponent = widget.NewEntry()
+	a.logComponent.SetPlaceHolder("Component")
+	a.logConnection = widget.NewEntry()
+	a.logConnection.SetPlaceHolder("Connection ID")
+	a.logSearch = widget.NewEntry()
+	a.logSearch.SetPlaceHolder("Search")
+
+	for _, entry := range []*widget.Entry{a.logComponent, a.logConnection, a.logSearch} {
+		entry.OnChanged = func(string) {
+			a.applyLogFilter()
+		}
+	}
+
+	a.logFollow = widget.NewCheck("Follow", a.logView.SetFollow)
+	a.logFollow.SetChecked(true)
+	a.logDebug = widget.NewCheck("Debug", a.setDebugLogging)
+}
+
+// setDebugLogging switches sing-box to debug logging, or back to the level
+// chosen in Settings before, restarting it if it is running.
+func (a *App) setDebugLogging(enabled bool) {
+	settings := a.currentSettings()
+	if enabled {
+		a.prevLogLevel = settings.SingBoxLogLevel
+		settings.SingBoxLogLevel = "debug"
+	} else {
+		settings.SingBoxLogLevel = a.prevLogLevel
+		if settings.SingBoxLogLevel == "debug" || settings.SingBoxLogLevel == "trace" {
+			settings.SingBoxLogLevel = ""
+		}
+	}
+	a.saveSettings(settings)
+}
+
+func (a *App) applyLogFilter() {
+	filter := logFilter{
+		component:    strings.TrimSpace(a.logComponent.Text),
+		connectionID: strings.TrimSpace(a.logConnection.Text),
+		search:       a.logSearch.Text,
+	}
+	if a.logLevel.Selected != allLogLevels {
+		filter.minLevel = a.logLevel.Selected
+	}
+	a.logView.SetFilter(filter)
+}
 
-	a.logWatcher = NewLogWatcher(logPath)
+func (a *App) startLogWatcher() {
+	a.logWatcher = NewLogWatcher(a.configFetcher.Paths().SingBoxLog(), a)
 	a.logWatcher.Start()
 }
 
@@ -56,35 +143,102 @@ func (a *App) stopLogWatcher() {
 	}
 }
 
-func (a *App) refreshLogsUI() {
-	var newLogs []string
+// openAppLogFile starts writing go-sing's own messages to a rotating file in
+// the logs directory.
+func (a *App) openAppLogFile() {
+	path := a.configFetcher.Paths().AppLog()
+	file, err := logrotate.Open(path, config.AppLogPolicy)
+	if err != nil {
+		a.Log("Warning: Could not open app log file: " + err.Error())
+		return
+	}
+	a.appLogFile = file
 
-	for {
-		select {
-		case log := <-a.appLogs:
-			newLogs = append(newLogs, log)
-		default:
+	go func() {
+		if err := logrotate.Prune(path, config.AppLogPolicy); err != nil {
+			a.Log("Warning: Could not clean up app logs: " + err.Error())
 		}
-		break
+	}()
+}
+
+func (a *App) closeAppLogFile() {
+	if a.appLogFile == nil {
+		return
 	}
+	a.drainAppLogs()
+	a.appLogFile.Close()
+}
 
-	if a.logWatcher != nil {
-		watcherMessages := a.logWatcher.GetNewMessages()
-		if len(watcherMessages) > 0 {
-			for i := 0; i < len(watcherMessages) && i < maxLogsPerSecInUI; i++ {
-				newLogs = append(newLogs, watcherMessages[i])
-			}
-			if len(watcherMessages) > maxLogsPerSecInUI {
-				summary := fmt.Sprintf("[%d more sing-box messages...]", len(watcherMessages)-maxLogsPerSecInUI)
-				newLogs = append(newLogs, summary)
-			}
-		}
+// drainAppLogs takes the queued app messages, reporting any that were
+// dropped, and appends them to the app log file.
+func (a *App) drainAppLogs() []LogEntry {
+	entries, dropped := a.appLogs.Drain()
+	if dropped > 0 {
+		entries = append(entries, LogEntry{
+			Time:   time.Now(),
+			Source: LogSourceApp,
+			Level:  "WARN",
+			Text:   fmt.Sprintf("Warning: %d app log messages were dropped because the log queue was full", dropped),
+		})
 	}
 
-	if len(newLogs) > 0 && a.logsText != nil {
-		for _, logEntry := range newLogs {
-			a.logBuffer += logEntry + "\n"
+	if a.appLogFile != nil && len(entries) > 0 {
+		var text strings.Builder
+		for _, entry := range entries {
+			fmt.Fprintf(&text, "%s %-5s %s\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Level, entry.Text)
 		}
-		a.logsText.ParseMarkdown("```\n" + a.logBuffer + "\n```")
+		a.appLogFile.Write([]byte(text.String()))
 	}
+	return entries
+}
+
+func (a *App) refreshLogsUI() {
+	newLogs := a.drainAppLogs()
+
+	if a.logWatcher != nil {
+		newLogs = append(newLogs, a.logWatcher.GetNewMessages()...)
+	}
+
+	a.logStore.Append(newLogs...)
+	// Called every log view refresh interval, which bounds how often the
+	// list is rendered however fast sing-box logs
+	fyne.Do(a.logView.Update)
+}
+
+// handleExportDiagnostics saves a zip for bug reports. Credentials are always
+// redacted; "Hide servers" applies as for copying.
+func (a *App) handleExportDiagnostics() {
+	if a.vpnController == nil {
+		return
+	}
+	hideServers := a.hideServers.Checked
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			a.Log("Error choosing diagnostics file: " + err.Error())
+			return
+		}
+		if writer == nil {
+			return
+		}
+		path := writer.URI().Path()
+		writer.Close()
+
+		go func() {
+			err := a.vpnController.ExportDiagnostics(path, hideServers)
+			if err != nil {
+				a.Log("Error exporting diagnostics: " + err.Error())
+				fyne.Do(func() {
+					dialog.ShowError(err, a.window)
+				})
+				return
+			}
+			fyne.Do(func() {
+				dialog.ShowInformation("Diagnostics exported", "Saved to "+path+"\nPlease check it before sharing.", a.window)
+			})
+		}()
+	}, a.window)
+	saveDialog.SetFileName(vpn.DiagnosticsFileName(time.Now()))
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+	saveDialog.Show()
 }
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LogSourceApp     = "app"
+	LogSourceSingBox = "sing-box"
+
+	maxLogEntries = 10000
+)
+
+// LogEntry is one line in the logs view.
+type LogEntry struct {
+	Time         time.Time
+	Source       string
+	Level        string
+	Component    string
+	ConnectionID string
+	Text         string
+}
+
+func (e LogEntry) String() string {
+	if e.Source == LogSourceSingBox {
+		return "[sing-box LOG] " + e.Text
+	}
+	return fmt.Sprintf("[%s] %s", e.Time.Format("15:04:05"), e.Text)
+}
+
+// appLogLevel infers the level of an app message from its wording.
+func appLogLevel(message string) string {
+	switch {
+	case strings.HasPrefix(message, "Error"):
+		return "ERROR"
+	case strings.HasPrefix(message, "Warning"):
+		return "WARN"
+	default:
+		return "INFO"
+	}
+}
+
+// LogStore keeps the most recent log entries in a fixed-size ring buffer.
+type LogStore struct {
+	mutex   sync.RWMutex
+	entries []LogEntry
+	start   int
+	count   int
+	evicted int
+}
+
+func NewLogStore(capacity int) *LogStore {
+	return &LogStore{
+		entries: make([]LogEntry, capacity),
+	}
+}
+
+// Append adds entries, evicting the oldest ones once the store is full.
+func (s *LogStore) Append(entries ...LogEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	capacity := len(s.entries)
+	for _, entry := range entries {
+		if s.count < capacity {
+			s.entries[(s.start+s.count)%capacity] = entry
+			s.count++
+			continue
+		}
+		s.entries[s.start] = entry
+		s.start = (s.start + 1) % capacity
+		s.evicted++
+	}
+}
+
+func (s *LogStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.count
+}
+
+// At returns the i-th oldest entry still in the store.
+func (s *LogStore) At(i int) LogEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if i < 0 || i >= s.count {
+		return LogEntry{}
+	}
+	return s.entries[(s.start+i)%len(s.entries)]
+}
+
+// Since returns the entries from absolute position seq on, counting evicted
+// entries, along with the position of the first one returned.
+func (s *LogStore) Since(seq int) ([]LogEntry, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	first := max(seq, s.evicted)
+	entries := make([]LogEntry, 0, max(0, s.evicted+s.count-first))
+	for i := first - s.evicted; i < s.count; i++ {
+		entries = append(entries, s.entries[(s.start+i)%len(s.entries)])
+	}
+	return entries, first
+}
+
+// Evicted returns how many entries have been dropped to make room so far.
+func (s *LogStore) Evicted() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.evicted
+}
+
+func (s *LogStore) Entries() []LogEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]LogEntry, s.count)
+	for i := range entries {
+		entries[i] = s.entries[(s.start+i)%len(s.entries)]
+	}
+	return entries
+}
+
+// Text renders all entries, one per line.
+func (s *LogStore) Text() string {
+	var text strings.Builder
+	for _, entry := range s.Entries() {
+		text.WriteString(entry.String())
+		text.WriteByte('\n')
+	}
+	return text.String()
+}
+
+// LogQueue hands log entries from any goroutine to the UI without ever
+// blocking the caller. Once limit entries are pending, further ones are
+// counted and dropped until the queue is drained.
+type LogQueue struct {
+	mutex   sync.Mutex
+	pending []LogEntry
+	limit   int
+	dropped int
+}
+
+func NewLogQueue(limit int) *LogQueue {
+	return &LogQueue{limit: limit}
+}
+
+func (q *LogQueue) Push(entry LogEntry) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.pending) >= q.limit {
+		q.dropped++
+		return
+	}
+	q.pending = append(q.pending, entry)
+}
+
+// Drain takes all pending entries along with the number dropped since the
+// last call.
+func (q *LogQueue) Drain() ([]LogEntry, int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	entries, dropped := q.pending, q.dropped
+	q.pending = nil
+	q.dropped = 0
+	return entries, dropped
+}
@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// logFilter selects the entries shown in the logs view.
+type logFilter struct {
+	minLevel     string
+	component    string
+	connectionID string
+	search       string
+}
+
+func (f logFilter) active() bool {
+	return f.minLevel != "" || f.component != "" || f.connectionID != "" || f.search != ""
+}
+
+func (f logFilter) matches(entry LogEntry) bool {
+	if f.minLevel != "" && logLevelRank(entry.Level) < logLevelRank(f.minLevel) {
+		return false
+	}
+	if f.component != "" && !strings.Contains(entry.Component, f.component) {
+		return false
+	}
+	if f.connectionID != "" && entry.ConnectionID != f.connectionID {
+		return false
+	}
+	if f.search != "" && !strings.Contains(strings.ToLower(entry.Text), strings.ToLower(f.search)) {
+		return false
+	}
+	return true
+}
+
+// logView shows a LogStore in a virtualized list, so only visible rows are
+// rendered no matter how many entries the store holds.
+type logView struct {
+	store     *LogStore
+	list      *widget.List
+	rowHeight float32
+	filter    logFilter
+	// matches holds the absolute positions of the entries passing the
+	// filter while one is active
+	matches  []int
+	shownLen int
+	evicted  int
+	nextSeq  int
+	follow   bool
+}
+
+func newLogView(store *LogStore) *logView {
+	v := &logView{store: store, follow: true}
+
+	v.list = widget.NewList(
+		func() int {
+			return v.shownLen
+		},
+		func() fyne.CanvasObject {
+			text := widget.NewRichText()
+			text.Truncation = fyne.TextTruncateEllipsis
+			return text
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			text := item.(*widget.RichText)
+			text.Segments = v.segments(v.entry(id))
+			text.Refresh()
+		},
+	)
+	v.list.OnSelected = func(id widget.ListItemID) {
+		v.list.Unselect(id)
+	}
+
+	template := widget.NewRichTextWithText("")
+	v.rowHeight = template.MinSize().Height + theme.Padding()
+
+	return v
+}
+
+func (v *logView) entry(id widget.ListItemID) LogEntry {
+	if !v.filter.active() {
+		return v.store.At(v.evicted + id - v.store.Evicted())
+	}
+	if id >= len(v.matches) {
+		return LogEntry{}
+	}
+	return v.store.At(v.matches[id] - v.store.Evicted())
+}
+
+// segments renders an entry, coloring warnings and errors and highlighting
+// search matches.
+func (v *logView) segments(entry LogEntry) []widget.RichTextSegment {
+	color := theme.ColorNameForeground
+	switch logLevelRank(entry.Level) {
+	case 3:
+		color = theme.ColorNameWarning
+	case 4, 5, 6:
+		color = theme.ColorNameError
+	}
+	plain := widget.RichTextStyle{
+		ColorName: color,
+		Inline:    true,
+		TextStyle: fyne.TextStyle{Monospace: true},
+	}
+	highlight := widget.RichTextStyle{
+		ColorName: theme.ColorNamePrimary,
+		Inline:    true,
+		TextStyle: fyne.TextStyle{Monospace: true, Bold: true},
+	}
+
+	text := entry.String()
+	search := strings.ToLower(v.filter.search)
+	lower := strings.ToLower(text)
+	if search == "" || len(lower) != len(text) {
+		return []widget.RichTextSegment{&widget.TextSegment{Text: text, Style: plain}}
+	}
+
+	var segments []widget.RichTextSegment
+	for {
+		i := strings.Index(lower, search)
+		if i < 0 {
+			break
+		}
+		if i > 0 {
+			segments = append(segments, &widget.TextSegment{Text: text[:i], Style: plain})
+		}
+		segments = append(segments, &widget.TextSegment{Text: text[i : i+len(search)], Style: highlight})
+		text, lower = text[i+len(search):], lower[i+len(search):]
+	}
+	if text != "" {
+		segments = append(segments, &widget.TextSegment{Text: text, Style: plain})
+	}
+	return segments
+}
+
+// SetFilter re-filters the entries shown so far, scrolling to the newest
+// match only while following. Must run on the UI thread.
+func (v *logView) SetFilter(filter logFilter) {
+	horizon := v.nextSeq
+
+	v.filter = filter
+	v.matches = nil
+	v.nextSeq = 0
+	v.evicted = v.store.Evicted()
+	v.shownLen = 0
+	v.reveal(horizon)
+	v.list.Refresh()
+	if v.follow {
+		v.list.ScrollToBottom()
+	}
+}
+
+// SetFollow turns automatic scrolling to new entries on or off. While off,
+// new entries are still added below but the view stays where it is.
+func (v *logView) SetFollow(follow bool) {
+	v.follow = follow
+	if follow {
+		v.list.ScrollToBottom()
+	}
+}
+
+// Update shows the entries added since the last call, refreshing the list
+// once for all of them. Must run on the UI thread.
+func (v *logView) Update() {
+	v.reveal(v.store.Evicted() + v.store.Len())
+}
+
+// reveal adds the entries before absolute position until to the view. While
+// following and scrolled to the bottom the view keeps up with new entries,
+// otherwise it stays on the lines being read, even as old entries are
+// evicted.
+func (v *logView) reveal(until int) {
+	entries, first := v.store.Since(v.nextSeq)
+	entries = entries[:max(0, min(len(entries), until-first))]
+	evicted := v.store.Evicted()
+	if len(entries) == 0 && evicted == v.evicted {
+		return
+	}
+	v.nextSeq = first + len(entries)
+
+	following := v.follow && v.atBottom()
+	offset := v.list.GetScrollOffset()
+
+	var removed int
+	if v.filter.active() {
+		for i, entry := range entries {
+			if v.filter.matches(entry) {
+				v.matches = append(v.matches, first+i)
+			}
+		}
+		for removed < len(v.matches) && v.matches[removed] < evicted {
+			removed++
+		}
+		v.matches = v.matches[removed:]
+		v.shownLen = len(v.matches)
+	} else {
+		removed = evicted - v.evicted
+		v.shownLen = v.nextSeq - evicted
+	}
+	v.evicted = evicted
+	v.list.Refresh()
+
+	if following {
+		v.list.ScrollToBottom()
+	} else if removed > 0 {
+		v.list.ScrollToOffset(max(0, offset-float32(removed)*v.rowHeight))
+	}
+}
+
+func (v *logView) atBottom() bool {
+	contentHeight := float32(v.shownLen) * v.rowHeight
+	return v.list.GetScrollOffset()+v.list.Size().Height >= contentHeight-v.rowHeight
+}
@@ -2,35 +2,57 @@ package ui
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"fmt"
+	"errors"
+	"go-sing/config"
+	"go-sing/internal/logrotate"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
 )
 
+// maxLogLineLength caps a single sing-box line; longer ones are cut short.
+const maxLogLineLength = 1 << 20
+
+// LogWatcher tails the sing-box log. It reads when the file changes and also
+// checks at the poll interval, in case change notifications are unavailable
+// or delayed. Only the watch goroutine touches the file state.
 type LogWatcher struct {
-	logPath       string
-	ctx           context.Context
-	cancel        context.CancelFunc
-	lastOffset    int64
-	newMessages   []string
-	messagesMutex sync.Mutex
-	fileExists    bool
+	logPath         string
+	logger          Logger
+	ctx             context.Context
+	cancel          context.CancelFunc
+	newMessages     []LogEntry
+	messagesMutex   sync.Mutex
+	pollInterval    time.Duration
+	intervalChanged chan time.Duration
+
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64
+	partial []byte
 }
 
 type Logger interface {
 	Log(message string)
 }
 
-func NewLogWatcher(logPath string) *LogWatcher {
+func NewLogWatcher(logPath string, logger Logger) *LogWatcher {
 	ctx, cancel := context.WithCancel(context.Background())
 	lw := &LogWatcher{
-		logPath: logPath,
-		ctx:     ctx,
-		cancel:  cancel,
+		logPath:         filepath.Clean(logPath),
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+		pollInterval:    config.DefaultSettings().LogPollInterval(),
+		intervalChanged: make(chan time.Duration, 1),
 	}
 
 	lw.archiveExistingLogFile()
@@ -46,111 +68,201 @@ func (lw *LogWatcher) Stop() {
 	lw.cancel()
 }
 
+// SetPollInterval changes how often the log file is checked for new lines
+// without a change notification.
+func (lw *LogWatcher) SetPollInterval(interval time.Duration) {
+	select {
+	case <-lw.intervalChanged:
+	default:
+	}
+	lw.intervalChanged <- interval
+}
+
 func (lw *LogWatcher) watchLoop() {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	defer lw.closeFile()
+
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	watcher, err := lw.newNotifier()
+	if err != nil {
+		lw.logger.Log("Warning: Could not watch the sing-box log for changes, polling instead: " + err.Error())
+	} else {
+		defer watcher.Close()
+		events = watcher.Events
+		watchErrors = watcher.Errors
+	}
+
+	ticker := time.NewTicker(lw.pollInterval)
 	defer ticker.Stop()
 
+	lw.readNewLines()
+
 	for {
 		select {
 		case <-lw.ctx.Done():
 			return
+		case interval := <-lw.intervalChanged:
+			ticker.Reset(interval)
 		case <-ticker.C:
 			lw.readNewLines()
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if filepath.Clean(event.Name) == lw.logPath {
+				lw.readNewLines()
+			}
+		case err, ok := <-watchErrors:
+			if !ok {
+				watchErrors = nil
+				continue
+			}
+			lw.logger.Log("Warning: sing-box log watcher: " + err.Error())
 		}
 	}
 }
 
-func (lw *LogWatcher) readNewLines() {
-	file, err := lw.openLogFile()
+// newNotifier watches the logs directory rather than the file, so creation
+// and replacement of the log are noticed too.
+func (lw *LogWatcher) newNotifier() (*fsnotify.Watcher, error) {
+	dir := filepath.Dir(lw.logPath)
+	err := os.MkdirAll(dir, 0700)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer file.Close()
 
-	currentSize, err := lw.getFileSize(file)
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		return
+		return nil, err
+	}
+	err = watcher.Add(dir)
+	if err != nil {
+		watcher.Close()
+		return nil, err
 	}
+	return watcher, nil
+}
 
-	if !lw.shouldReadFile(currentSize) {
+// readNewLines reads whatever was appended since the last call. A log that
+// shrank was truncated and is read again from the start; when the path now
+// names a different file, the rest of the old one is read before switching.
+func (lw *LogWatcher) readNewLines() {
+	if lw.file == nil && !lw.openFile() {
 		return
 	}
 
-	if err := lw.seekToLastPosition(file); err != nil {
+	info, err := lw.file.Stat()
+	if err == nil && info.Size() < lw.offset {
+		lw.rewind()
+	}
+	lw.readAvailable()
+
+	current, err := os.Stat(lw.logPath)
+	if err == nil && os.SameFile(info, current) {
 		return
 	}
 
-	lw.processNewLines(file)
-	lw.lastOffset = currentSize
+	lw.flushPartial()
+	lw.closeFile()
+	if err == nil && lw.openFile() {
+		lw.readAvailable()
+	}
 }
 
-func (lw *LogWatcher) openLogFile() (*os.File, error) {
-	file, err := os.Open(lw.logPath)
+func (lw *LogWatcher) openFile() bool {
+	file, err := openShared(lw.logPath)
 	if err != nil {
-		lw.fileExists = false
-		return nil, err
+		return false
 	}
-	return file, nil
+	lw.file = file
+	lw.reader = bufio.NewReader(file)
+	lw.offset = 0
+	lw.partial = nil
+	return true
 }
 
-func (lw *LogWatcher) getFileSize(file *os.File) (int64, error) {
-	stat, err := file.Stat()
-	if err != nil {
-		return 0, err
+func (lw *LogWatcher) closeFile() {
+	if lw.file != nil {
+		lw.file.Close()
+		lw.file = nil
+		lw.reader = nil
 	}
-	return stat.Size(), nil
 }
 
-func (lw *LogWatcher) shouldReadFile(currentSize int64) bool {
-	if !lw.fileExists {
-		lw.fileExists = true
-		lw.lastOffset = 0
-		return true
-	}
-	
-	if currentSize < lw.lastOffset {
-		// File was reset/truncated
-		lw.lastOffset = 0
-		return true
+func (lw *LogWatcher) rewind() {
+	_, err := lw.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return
 	}
-	
-	// No new content
-	return currentSize > lw.lastOffset
+	lw.reader.Reset(lw.file)
+	lw.offset = 0
+	lw.partial = nil
 }
 
-func (lw *LogWatcher) seekToLastPosition(file *os.File) error {
-	_, err := file.Seek(lw.lastOffset, 0)
-	return err
-}
+// readAvailable reads up to the end of the file. A trailing line without a
+// newline is kept until the rest of it is written.
+func (lw *LogWatcher) readAvailable() {
+	var newMessages []LogEntry
 
-func (lw *LogWatcher) processNewLines(file *os.File) {
-	scanner := bufio.NewScanner(file)
-	var newMessages []string
-	
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+	for {
+		chunk, err := lw.reader.ReadSlice('\n')
+		lw.offset += int64(len(chunk))
+		if len(lw.partial) < maxLogLineLength {
+			lw.partial = append(lw.partial, chunk[:min(len(chunk), maxLogLineLength-len(lw.partial))]...)
 		}
-		
-		cleanLine := lw.cleanLogLine(line)
-		if cleanLine == "" {
+
+		if errors.Is(err, bufio.ErrBufferFull) {
 			continue
 		}
-		
-		message := fmt.Sprintf("[sing-box LOG] %s", cleanLine)
-		newMessages = append(newMessages, message)
+		if err != nil {
+			break
+		}
+
+		if entry, ok := lw.parseLine(lw.partial); ok {
+			newMessages = append(newMessages, entry)
+		}
+		lw.partial = lw.partial[:0]
+	}
+
+	lw.addMessages(newMessages)
+}
+
+// flushPartial emits a last line that never got its newline.
+func (lw *LogWatcher) flushPartial() {
+	if entry, ok := lw.parseLine(lw.partial); ok {
+		lw.addMessages([]LogEntry{entry})
+	}
+	lw.partial = nil
+}
+
+func (lw *LogWatcher) parseLine(raw []byte) (LogEntry, bool) {
+	line := strings.TrimSpace(string(bytes.TrimRight(raw, "\r\n")))
+	if line == "" {
+		return LogEntry{}, false
+	}
+
+	cleanLine := lw.cleanLogLine(line)
+	if cleanLine == "" {
+		return LogEntry{}, false
 	}
-	
-	if len(newMessages) > 0 {
-		lw.messagesMutex.Lock()
-		lw.newMessages = append(lw.newMessages, newMessages...)
-		lw.messagesMutex.Unlock()
+
+	return parseSingBoxLine(config.RedactSecrets(cleanLine)), true
+}
+
+func (lw *LogWatcher) addMessages(messages []LogEntry) {
+	if len(messages) == 0 {
+		return
 	}
+	lw.messagesMutex.Lock()
+	lw.newMessages = append(lw.newMessages, messages...)
+	lw.messagesMutex.Unlock()
 }
 
 func (lw *LogWatcher) cleanLogLine(line string) string {
 
+	line = ansiEscape.ReplaceAllString(line, "")
+
 	if !utf8.ValidString(line) {
 		line = strings.ToValidUTF8(line, "?")
 	}
@@ -185,22 +297,22 @@ func (lw *LogWatcher) cleanLogLine(line string) string {
 	return strings.TrimSpace(result.String())
 }
 
+// archiveExistingLogFile moves the previous run's log aside before sing-box
+// starts writing, then compresses and prunes the archives in the background.
 func (lw *LogWatcher) archiveExistingLogFile() {
-
-	if _, err := os.Stat(lw.logPath); os.IsNotExist(err) {
-		return
-	}
-
-	timestamp := time.Now().Format("20060102_150405")
-	archivePath := fmt.Sprintf("%s.%s", lw.logPath, timestamp)
-
-	if err := os.Rename(lw.logPath, archivePath); err != nil {
-
+	if _, err := logrotate.Archive(lw.logPath); err != nil {
+		lw.logger.Log("Warning: " + err.Error())
 		os.Remove(lw.logPath)
 	}
+
+	go func() {
+		if err := logrotate.Prune(lw.logPath, config.SingBoxLogPolicy); err != nil {
+			lw.logger.Log("Warning: Could not clean up sing-box logs: " + err.Error())
+		}
+	}()
 }
 
-func (lw *LogWatcher) GetNewMessages() []string {
+func (lw *LogWatcher) GetNewMessages() []LogEntry {
 	lw.messagesMutex.Lock()
 	defer lw.messagesMutex.Unlock()
 
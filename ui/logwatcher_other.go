@@ -0,0 +1,9 @@
+//go:build !windows
+
+package ui
+
+import "os"
+
+func openShared(path string) (*os.File, error) {
+	return os.Open(path)
+}
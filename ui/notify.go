@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"go-sing/config"
+	"go-sing/vpn"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// notificationInterval limits each kind of notification, so a crash loop or
+// a flapping connection does not flood the desktop.
+const notificationInterval = time.Minute
+
+// notificationStreams groups events that report the same thing, here the
+// connection state, so they share a rate limit and the latest one wins.
+var notificationStreams = map[string]string{
+	config.NotifyConnected:    "connection",
+	config.NotifyDisconnected: "connection",
+	config.NotifyCrashed:      "connection",
+}
+
+var notificationLabels = map[string]string{
+	config.NotifyConnected:     "Connected",
+	config.NotifyDisconnected:  "Disconnected",
+	config.NotifyCrashed:       "sing-box crashed or failed to start",
+	config.NotifyConfigChanged: "Subscription config changed",
+	config.NotifyCoreUpdated:   "sing-box updated",
+	config.NotifyQuota:         "Traffic quota or expiry warnings",
+}
+
+// notify shows a desktop notification for event unless it is turned off in
+// Settings. Within notificationInterval of the last one for the same stream
+// it is held back instead, and only the latest held one is shown once the
+// interval has passed.
+func (a *App) notify(event, title, content string) {
+	select {
+	case <-a.uiReady:
+	default:
+		return
+	}
+
+	stream, ok := notificationStreams[event]
+	if !ok {
+		stream = event
+	}
+
+	a.notifyMutex.Lock()
+	defer a.notifyMutex.Unlock()
+
+	if !a.currentSettings().Notifies(event) {
+		// A held back notification would now be out of date
+		delete(a.queuedNotices, stream)
+		return
+	}
+	notification := fyne.NewNotification(title, content)
+
+	wait := notificationInterval - time.Since(a.lastNotified[stream])
+	if wait > 0 {
+		_, scheduled := a.queuedNotices[stream]
+		a.queuedNotices[stream] = notification
+		if !scheduled {
+			time.AfterFunc(wait, func() {
+				a.sendQueuedNotification(stream)
+			})
+		}
+		return
+	}
+
+	a.lastNotified[stream] = time.Now()
+	a.sendNotification(notification)
+}
+
+func (a *App) sendQueuedNotification(stream string) {
+	a.notifyMutex.Lock()
+	defer a.notifyMutex.Unlock()
+
+	notification, ok := a.queuedNotices[stream]
+	if !ok {
+		return
+	}
+	delete(a.queuedNotices, stream)
+
+	a.lastNotified[stream] = time.Now()
+	a.sendNotification(notification)
+}
+
+func (a *App) sendNotification(notification *fyne.Notification) {
+	fyne.Do(func() {
+		a.fyneApp.SendNotification(notification)
+	})
+}
+
+// HandleTransition is called by the VPN controller whenever sing-box changes
+// state.
+func (a *App) HandleTransition(transition vpn.Transition) {
+	if state, ok := trayStateFor(transition); ok {
+		a.setTrayState(state)
+	}
+
+	switch transition.State {
+	case vpn.StateRunning:
+		a.notify(config.NotifyConnected, "Connected", "sing-box is running")
+	case vpn.StateStopped:
+		a.notify(config.NotifyDisconnected, "Disconnected", "sing-box was stopped")
+	case vpn.StateExited:
+		if transition.Detail == "" {
+			a.notify(config.NotifyDisconnected, "Disconnected", "sing-box exited")
+		} else {
+			a.notify(config.NotifyCrashed, "sing-box crashed", transition.Detail)
+		}
+	case vpn.StateGaveUp:
+		a.notify(config.NotifyCrashed, "sing-box keeps crashing", "Gave up restarting it after "+transition.Detail)
+	case vpn.StateStartFailed:
+		a.notify(config.NotifyCrashed, "Could not connect", transition.Detail)
+	case vpn.StateCoreUpdated:
+		a.notify(config.NotifyCoreUpdated, "sing-box updated", "Now using sing-box "+transition.Detail)
+	}
+}
+
+// checkSubscriptionQuota warns once about each new quota or expiry warning
+// reported by the subscription.
+func (a *App) checkSubscriptionQuota() {
+	info := a.configFetcher.SubscriptionInfo()
+	if info == nil {
+		return
+	}
+
+	warning := info.Warning(time.Now())
+	if warning == "" || warning == a.quotaWarning {
+		return
+	}
+	a.quotaWarning = warning
+
+	a.Log("Warning: " + warning)
+	a.notify(config.NotifyQuota, "Subscription", warning)
+}
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/config"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+func (a *App) handleProfileChanged(name string) {
+	if name == "" || name == a.profile {
+		return
+	}
+
+	go func() {
+		err := a.switchProfile(name)
+		if err != nil {
+			a.Log("Error switching profile: " + err.Error())
+			a.refreshProfiles()
+		}
+	}()
+}
+
+// switchProfile fetches the config of the named profile and makes it the one
+// in use, restarting sing-box if it is running. It blocks until done.
+func (a *App) switchProfile(name string) error {
+	profiles, current, err := a.configFetcher.Profiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	if name == current {
+		return nil
+	}
+
+	index := slices.IndexFunc(profiles, func(p config.Profile) bool { return p.Name == name })
+	if index < 0 {
+		return fmt.Errorf("no profile called %q", name)
+	}
+	url := profiles[index].URL
+
+	// Fetched first, so a failing subscription leaves the current one in use
+	a.Log("Switching to profile " + name + ", fetching configuration from: " + config.RedactURL(url))
+	_, err = a.configFetcher.FetchConfig(url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config: %w", err)
+	}
+
+	_, err = a.configFetcher.SwitchProfile(name)
+	if err != nil {
+		return err
+	}
+
+	a.configWatcher.UpdateURL(url)
+	fyne.Do(func() {
+		a.urlEntry.SetText(url)
+	})
+	a.refreshProfiles()
+	a.loadExistingSingBoxConfig()
+	a.Log("Switched to profile " + name)
+
+	if a.vpnController != nil && a.vpnController.IsRunning() {
+		a.Log("Restarting sing-box with the new profile...")
+		return a.vpnController.RestartVPN()
+	}
+	return nil
+}
+
+// refreshProfiles shows the saved profiles in the window and tray menu.
+func (a *App) refreshProfiles() {
+	profiles, current, err := a.configFetcher.Profiles()
+	if err != nil {
+		a.Log("Warning: Could not load profiles: " + err.Error())
+		return
+	}
+
+	var names []string
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+
+	fyne.Do(func() {
+		a.profile = current
+		a.profiles = names
+		a.profileSelect.SetOptions(names)
+		a.profileSelect.SetSelected(current)
+		a.refreshSystemTrayMenu()
+	})
+}
+
+func (a *App) handleRemoveProfile() {
+	var others []string
+	for _, name := range a.profiles {
+		if name != a.profile {
+			others = append(others, name)
+		}
+	}
+	if len(others) == 0 {
+		dialog.ShowInformation("Remove profile", "There are no other profiles. The profile in use cannot be removed.", a.window)
+		return
+	}
+
+	choice := widget.NewSelect(others, nil)
+	choice.SetSelected(others[0])
+	items := []*widget.FormItem{widget.NewFormItem("Profile", choice)}
+	dialog.ShowForm("Remove profile", "Remove", "Cancel", items, func(confirmed bool) {
+		if !confirmed || choice.Selected == "" {
+			return
+		}
+
+		name := choice.Selected
+		go func() {
+			err := a.configFetcher.RemoveProfile(name)
+			if err != nil {
+				a.Log("Error removing profile: " + err.Error())
+				return
+			}
+			a.Log("Removed profile " + name)
+			a.refreshProfiles()
+		}()
+	}, a.window)
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"go-sing/config"
+	"go-sing/internal/clipboard"
+	"go-sing/internal/qr"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+func (a *App) newQRButton() *widget.Button {
+	button := widget.NewButton("QR", nil)
+	button.OnTapped = func() {
+		menu := fyne.NewMenu("",
+			fyne.NewMenuItem("Import from image file...", a.handleImportQRFile),
+			fyne.NewMenuItem("Import from clipboard", a.handleImportQRClipboard),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Show subscription as QR code", a.handleShowQR),
+		)
+		position := fyne.CurrentApp().Driver().AbsolutePositionForObject(button)
+		widget.ShowPopUpMenuAtPosition(menu, a.window.Canvas(), position.AddXY(0, button.Size().Height))
+	}
+	return button
+}
+
+func (a *App) handleImportQRFile() {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			a.Log("Error opening image: " + err.Error())
+			return
+		}
+		if reader == nil {
+			return
+		}
+
+		go func() {
+			defer reader.Close()
+			img, _, err := image.Decode(reader)
+			if err != nil {
+				a.showImportError(fmt.Errorf("failed to read image: %w", err))
+				return
+			}
+			a.importFromQRImage(img)
+		}()
+	}, a.window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".jpg", ".jpeg", ".gif"}))
+	fileDialog.Show()
+}
+
+func (a *App) handleImportQRClipboard() {
+	text := a.window.Clipboard().Content()
+
+	go func() {
+		img, err := clipboard.ReadImage()
+		if err == nil {
+			a.importFromQRImage(img)
+			return
+		}
+
+		// Copying a share link as text works too
+		if importLink, textErr := config.ParseSharedSubscription(text); textErr == nil {
+			fyne.Do(func() {
+				a.confirmImport(importLink)
+			})
+			return
+		}
+
+		a.showImportError(err)
+	}()
+}
+
+// importFromQRImage decodes a QR code and offers its subscription for import.
+// Called off the UI thread.
+func (a *App) importFromQRImage(img image.Image) {
+	text, err := qr.Decode(img)
+	if err != nil {
+		a.showImportError(err)
+		return
+	}
+
+	importLink, err := config.ParseSharedSubscription(text)
+	if err != nil {
+		a.showImportError(fmt.Errorf("the QR code does not contain a subscription: %w", err))
+		return
+	}
+
+	fyne.Do(func() {
+		a.confirmImport(importLink)
+	})
+}
+
+func (a *App) showImportError(err error) {
+	a.Log("Error importing QR code: " + err.Error())
+	fyne.Do(func() {
+		dialog.ShowError(err, a.window)
+	})
+}
+
+// handleShowQR renders the saved subscription as a sing-box import link for
+// phones to scan.
+func (a *App) handleShowQR() {
+	appConfig, err := a.configFetcher.LoadAppConfig()
+	if err == nil && appConfig.SubscriptionURL == "" {
+		err = errors.New("no subscription URL saved yet")
+	}
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	importLink, err := config.ParseSharedSubscription(appConfig.SubscriptionURL)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	code, err := qrcode.New(importLink.SingBoxLink(), qrcode.Medium)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to create QR code: %w", err), a.window)
+		return
+	}
+
+	img := canvas.NewImageFromImage(code.Image(512))
+	img.FillMode = canvas.ImageFillContain
+	img.ScaleMode = canvas.ImageScalePixels
+	img.SetMinSize(fyne.NewSize(320, 320))
+
+	warning := widget.NewLabel("Anyone who scans this code can use your subscription.")
+	warning.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(nil, warning, nil, nil, img)
+	d := dialog.NewCustom("Subscription QR code", "Close", content, a.window)
+	d.Resize(fyne.NewSize(380, 440))
+	d.Show()
+	a.Log("Showing subscription QR code for " + config.RedactURL(appConfig.SubscriptionURL))
+}
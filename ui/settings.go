@@ -0,0 +1,257 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/config"
+	"go-sing/internal/autostart"
+	"go-sing/internal/urlscheme"
+	"os"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// providerLogLevel keeps the level set in the provider's config.
+const providerLogLevel = "From config"
+
+var restartPolicies = []string{config.RestartNever, config.RestartOnFailure, config.RestartAlways}
+
+func (a *App) currentSettings() config.Settings {
+	a.settingsMutex.RLock()
+	defer a.settingsMutex.RUnlock()
+	return a.settings
+}
+
+func (a *App) loadSettings() {
+	appConfig, err := a.configFetcher.LoadAppConfig()
+	if err != nil {
+		a.Log("Warning: Could not load settings, using defaults: " + err.Error())
+		a.applySettings(config.DefaultSettings())
+		return
+	}
+	a.applySettings(appConfig.Settings)
+}
+
+// applySettings pushes settings to the running components.
+func (a *App) applySettings(settings config.Settings) {
+	a.settingsMutex.Lock()
+	a.settings = settings
+	a.settingsMutex.Unlock()
+
+	if a.configWatcher != nil {
+		a.configWatcher.SetInterval(settings.ConfigWatchInterval())
+	}
+	if a.logWatcher != nil {
+		a.logWatcher.SetPollInterval(settings.LogPollInterval())
+	}
+	if a.vpnController != nil {
+		a.vpnController.ApplySettings(settings)
+	}
+	if a.logDebug != nil {
+		a.logDebug.Checked = settings.SingBoxLogLevel == "debug" || settings.SingBoxLogLevel == "trace"
+		a.logDebug.Refresh()
+	}
+	a.updateAPIServer(settings)
+}
+
+func (a *App) handleConfigChanged() {
+	a.notify(config.NotifyConfigChanged, "Config updated", "Your subscription config has changed")
+
+	if !a.currentSettings().HotReload || a.vpnController == nil || !a.vpnController.IsRunning() {
+		return
+	}
+
+	a.Log("Reloading sing-box with the updated config...")
+	err := a.vpnController.RestartVPN()
+	if err != nil {
+		a.Log("Error reloading sing-box: " + err.Error())
+	}
+}
+
+func (a *App) showSettingsDialog() {
+	settings := a.currentSettings()
+
+	configWatch := newIntEntry(settings.ConfigWatchIntervalSeconds)
+	binaryCheck := newIntEntry(settings.BinaryCheckIntervalSeconds)
+	logPoll := newIntEntry(settings.LogPollIntervalMillis)
+	logRefresh := newIntEntry(settings.LogViewRefreshMillis)
+
+	restartPolicy := widget.NewSelect(restartPolicies, nil)
+	restartPolicy.SetSelected(settings.RestartPolicy)
+
+	hotReload := widget.NewCheck("Restart sing-box when the config changes", nil)
+	hotReload.SetChecked(settings.HotReload)
+
+	autoConnect := widget.NewCheck("Connect when go-sing starts", nil)
+	autoConnect.SetChecked(settings.AutoConnect)
+
+	startMinimized := widget.NewCheck("Start minimized to the system tray", nil)
+	startMinimized.SetChecked(settings.StartMinimized)
+
+	launchAtLogin := widget.NewCheck("Launch go-sing when I log in", nil)
+	launchAtLogin.SetChecked(settings.LaunchAtLogin)
+
+	handleLinks := widget.NewCheck("Open sing-box:// and clash:// links with go-sing", nil)
+	handleLinks.SetChecked(settings.HandleLinks)
+
+	logLevel := widget.NewSelect(append([]string{providerLogLevel}, config.SingBoxLogLevels...), nil)
+	logLevel.SetSelected(settings.SingBoxLogLevel)
+	if settings.SingBoxLogLevel == "" {
+		logLevel.SetSelected(providerLogLevel)
+	}
+
+	logToFile := widget.NewCheck("Force sing-box to log to sing-box.log", nil)
+	logToFile.SetChecked(settings.SingBoxLogToFile)
+
+	notifications := container.NewVBox()
+	notificationChecks := map[string]*widget.Check{}
+	for _, event := range config.NotificationEvents {
+		check := widget.NewCheck(notificationLabels[event], nil)
+		check.SetChecked(settings.Notifies(event))
+		notificationChecks[event] = check
+		notifications.Add(check)
+	}
+
+	apiEnabled := widget.NewCheck("Allow scripts to control go-sing over HTTP", nil)
+	apiEnabled.SetChecked(settings.APIEnabled)
+	apiPort := newIntEntry(settings.APIPort)
+	apiToken := a.newAPITokenField()
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Config check interval (s)", configWatch),
+		widget.NewFormItem("sing-box check interval (s)", binaryCheck),
+		widget.NewFormItem("Log poll interval (ms)", logPoll),
+		widget.NewFormItem("Log view refresh (ms)", logRefresh),
+		widget.NewFormItem("Restart sing-box", restartPolicy),
+		widget.NewFormItem("Hot reload", hotReload),
+		widget.NewFormItem("sing-box log level", logLevel),
+		widget.NewFormItem("sing-box log output", logToFile),
+		widget.NewFormItem("Auto-connect", autoConnect),
+		widget.NewFormItem("Start minimized", startMinimized),
+		widget.NewFormItem("Launch at login", launchAtLogin),
+		widget.NewFormItem("Import links", handleLinks),
+		widget.NewFormItem("Notifications", notifications),
+		widget.NewFormItem("Local API", apiEnabled),
+		widget.NewFormItem("API port", apiPort),
+		widget.NewFormItem("API token", apiToken),
+	}
+
+	d := dialog.NewForm("Settings", "Save", "Cancel", items, func(save bool) {
+		if !save {
+			return
+		}
+
+		updated := settings
+		updated.ConfigWatchIntervalSeconds, _ = strconv.Atoi(configWatch.Text)
+		updated.BinaryCheckIntervalSeconds, _ = strconv.Atoi(binaryCheck.Text)
+		updated.LogPollIntervalMillis, _ = strconv.Atoi(logPoll.Text)
+		updated.LogViewRefreshMillis, _ = strconv.Atoi(logRefresh.Text)
+		updated.RestartPolicy = restartPolicy.Selected
+		updated.HotReload = hotReload.Checked
+		updated.SingBoxLogLevel = logLevel.Selected
+		if updated.SingBoxLogLevel == providerLogLevel {
+			updated.SingBoxLogLevel = ""
+		}
+		updated.SingBoxLogToFile = logToFile.Checked
+		updated.Notifications = map[string]bool{}
+		for event, check := range notificationChecks {
+			updated.Notifications[event] = check.Checked
+		}
+		updated.AutoConnect = autoConnect.Checked
+		updated.StartMinimized = startMinimized.Checked
+		updated.LaunchAtLogin = launchAtLogin.Checked
+		updated.HandleLinks = handleLinks.Checked
+		updated.APIEnabled = apiEnabled.Checked
+		updated.APIPort, _ = strconv.Atoi(apiPort.Text)
+
+		a.saveSettings(updated)
+	}, a.window)
+	d.Resize(fyne.NewSize(480, 0))
+	d.Show()
+}
+
+func (a *App) saveSettings(settings config.Settings) {
+	err := settings.Validate()
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	if settings.LaunchAtLogin != a.currentSettings().LaunchAtLogin {
+		err = setLaunchAtLogin(settings.LaunchAtLogin)
+		if err != nil {
+			a.Log("Error changing launch at login: " + err.Error())
+			settings.LaunchAtLogin = !settings.LaunchAtLogin
+		}
+	}
+
+	if settings.HandleLinks != a.currentSettings().HandleLinks {
+		err = setHandleLinks(settings.HandleLinks)
+		if err != nil {
+			a.Log("Error changing import link handling: " + err.Error())
+			settings.HandleLinks = !settings.HandleLinks
+		}
+	}
+
+	err = a.configFetcher.UpdateAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Settings = settings
+		return nil
+	})
+	if err != nil {
+		a.Log("Error saving settings: " + err.Error())
+		return
+	}
+
+	previous := a.currentSettings()
+	a.applySettings(settings)
+	a.Log("Settings saved")
+
+	logChanged := settings.SingBoxLogLevel != previous.SingBoxLogLevel || settings.SingBoxLogToFile != previous.SingBoxLogToFile
+	if logChanged && a.vpnController != nil && a.vpnController.IsRunning() {
+		go func() {
+			a.Log("Restarting sing-box to apply the log settings...")
+			if err := a.vpnController.RestartVPN(); err != nil {
+				a.Log("Error restarting sing-box: " + err.Error())
+			}
+		}()
+	}
+}
+
+func newIntEntry(value int) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetText(strconv.Itoa(value))
+	entry.Validator = func(text string) error {
+		if _, err := strconv.Atoi(text); err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		return nil
+	}
+	return entry
+}
+
+func setLaunchAtLogin(enabled bool) error {
+	if !enabled {
+		return autostart.Disable()
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return autostart.Enable(execPath)
+}
+
+func setHandleLinks(enabled bool) error {
+	if !enabled {
+		return urlscheme.Disable()
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return urlscheme.Enable(execPath)
+}
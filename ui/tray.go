@@ -0,0 +1,376 @@
+package ui
+
+import (
+	_ "embed"
+	"fmt"
+	"go-sing/vpn"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// Connection states shown by the tray icon.
+const (
+	trayDisconnected = "disconnected"
+	trayConnecting   = "connecting"
+	trayConnected    = "connected"
+	trayError        = "error"
+)
+
+var (
+	//go:embed icons/tray_disconnected.png
+	trayDisconnectedIcon []byte
+	//go:embed icons/tray_connecting.png
+	trayConnectingIcon []byte
+	//go:embed icons/tray_connected.png
+	trayConnectedIcon []byte
+	//go:embed icons/tray_error.png
+	trayErrorIcon []byte
+
+	trayIcons = map[string]fyne.Resource{
+		trayDisconnected: fyne.NewStaticResource("tray_disconnected.png", trayDisconnectedIcon),
+		trayConnecting:   fyne.NewStaticResource("tray_connecting.png", trayConnectingIcon),
+		trayConnected:    fyne.NewStaticResource("tray_connected.png", trayConnectedIcon),
+		trayError:        fyne.NewStaticResource("tray_error.png", trayErrorIcon),
+	}
+
+	trayStateLabels = map[string]string{
+		trayDisconnected: "Disconnected",
+		trayConnecting:   "Connecting...",
+		trayConnected:    "Connected",
+		trayError:        "Error",
+	}
+)
+
+// trayOutboundsInterval limits how often the tray asks sing-box which
+// outbounds are selected while the connection state stays the same.
+const trayOutboundsInterval = 10 * time.Second
+
+// trayMenuState is what the tray menu and tooltip show, so they are only
+// rebuilt when it changes.
+type trayMenuState struct {
+	state         string
+	startLabel    string
+	startDisabled bool
+	stopDisabled  bool
+	channel       string
+	channels      []string
+	profile       string
+	profiles      []string
+	outbounds     []vpn.OutboundGroup
+}
+
+func (s trayMenuState) equal(other trayMenuState) bool {
+	return s.state == other.state &&
+		s.startLabel == other.startLabel &&
+		s.startDisabled == other.startDisabled &&
+		s.stopDisabled == other.stopDisabled &&
+		s.channel == other.channel &&
+		slices.Equal(s.channels, other.channels) &&
+		s.profile == other.profile &&
+		slices.Equal(s.profiles, other.profiles) &&
+		slices.EqualFunc(s.outbounds, other.outbounds, func(a, b vpn.OutboundGroup) bool {
+			return a.Name == b.Name && a.Type == b.Type && a.Now == b.Now && slices.Equal(a.All, b.All)
+		})
+}
+
+// trayStateFor maps a controller state change to the tray icon to show. The
+// second result is false for changes that do not affect the connection.
+func trayStateFor(transition vpn.Transition) (string, bool) {
+	switch transition.State {
+	case vpn.StateStarting, vpn.StateRestarting:
+		return trayConnecting, true
+	case vpn.StateRunning:
+		return trayConnected, true
+	case vpn.StateStopped:
+		return trayDisconnected, true
+	case vpn.StateExited:
+		if transition.Detail == "" {
+			return trayDisconnected, true
+		}
+		return trayError, true
+	case vpn.StateStartFailed, vpn.StateGaveUp:
+		return trayError, true
+	}
+	return "", false
+}
+
+func (a *App) setupSystemTray() {
+	if desk, ok := a.fyneApp.(desktop.App); ok {
+		a.trayStartItem = fyne.NewMenuItem("Start VPN", a.handleStartVPN)
+		a.trayStopItem = fyne.NewMenuItem("Stop VPN", a.handleStopVPN)
+		a.trayStopItem.Disabled = true
+
+		a.trayState = trayDisconnected
+		desk.SetSystemTrayIcon(trayIcons[a.trayState])
+		a.refreshSystemTrayMenu()
+	}
+}
+
+// setTrayState swaps the tray icon when the connection state changes.
+func (a *App) setTrayState(state string) {
+	select {
+	case <-a.uiReady:
+	default:
+		return
+	}
+
+	fyne.Do(func() {
+		desk, ok := a.fyneApp.(desktop.App)
+		if !ok || a.trayState == state {
+			return
+		}
+		a.trayState = state
+		desk.SetSystemTrayIcon(trayIcons[state])
+		a.refreshSystemTrayMenu()
+	})
+
+	if state == trayConnected {
+		go a.refreshTrayOutbounds(true)
+	}
+}
+
+func (a *App) updateTrayItems(startEnabled, stopEnabled bool) {
+	if a.trayStartItem == nil || a.trayStopItem == nil {
+		return
+	}
+
+	label := a.getStartButtonLabel()
+
+	fyne.Do(func() {
+		a.trayStartItem.Disabled = !startEnabled
+		a.trayStartItem.Label = label
+		a.trayStopItem.Disabled = !stopEnabled
+		a.refreshSystemTrayMenu()
+	})
+
+	a.refreshTrayOutbounds(false)
+}
+
+// refreshTrayOutbounds asks sing-box for its outbounds at most once per
+// trayOutboundsInterval, or right away when force is set.
+func (a *App) refreshTrayOutbounds(force bool) {
+	var outbounds []vpn.OutboundGroup
+	if a.vpnController != nil && a.vpnController.IsRunning() {
+		a.trayMutex.Lock()
+		due := force || time.Since(a.trayFetchedAt) >= trayOutboundsInterval
+		if due {
+			a.trayFetchedAt = time.Now()
+		}
+		a.trayMutex.Unlock()
+		if !due {
+			return
+		}
+
+		groups, err := a.vpnController.Outbounds()
+		if err != nil {
+			return
+		}
+		outbounds = groups
+	} else {
+		a.trayMutex.Lock()
+		a.trayFetchedAt = time.Time{}
+		a.trayMutex.Unlock()
+	}
+
+	fyne.Do(func() {
+		a.trayOutbounds = outbounds
+		a.refreshSystemTrayMenu()
+	})
+}
+
+func (a *App) getStartButtonLabel() string {
+	if a.vpnController == nil {
+		return "Start VPN (Initializing...)"
+	}
+	if a.vpnController.ClientUpdateRequired() {
+		return "Start VPN (Update Required)"
+	}
+	if !a.vpnController.IsSingBoxAvailable() {
+		return "Start VPN (Downloading...)"
+	}
+	if !a.configExists() {
+		return "Start VPN (No Config)"
+	}
+	return "Start VPN"
+}
+
+// refreshSystemTrayMenu rebuilds the tray menu and tooltip, skipping the
+// rebuild when nothing shown in them has changed.
+func (a *App) refreshSystemTrayMenu() {
+	desk, ok := a.fyneApp.(desktop.App)
+	if !ok {
+		return
+	}
+
+	var channels []string
+	var current string
+	if a.vpnController != nil {
+		channels = a.vpnController.AvailableChannels()
+		current = a.vpnController.CurrentChannel()
+	}
+
+	menuState := trayMenuState{
+		state:         a.trayState,
+		startLabel:    a.trayStartItem.Label,
+		startDisabled: a.trayStartItem.Disabled,
+		stopDisabled:  a.trayStopItem.Disabled,
+		channel:       current,
+		channels:      channels,
+		profile:       a.profile,
+		profiles:      a.profiles,
+		outbounds:     a.trayOutbounds,
+	}
+	if menuState.equal(a.trayMenu) {
+		return
+	}
+	a.trayMenu = menuState
+
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem("Show", func() {
+			a.window.Show()
+		}),
+		a.trayStartItem,
+		a.trayStopItem,
+		fyne.NewMenuItemSeparator(),
+	}
+	if len(a.profiles) > 1 {
+		items = append(items, a.trayProfileMenu())
+	}
+	if len(channels) > 1 {
+		items = append(items, a.trayChannelMenu(channels, current))
+	}
+	if selectors := selectorGroups(a.trayOutbounds); len(selectors) > 0 {
+		items = append(items, a.trayOutboundMenu(selectors))
+	}
+	items = append(items,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Quit", func() {
+			a.fyneApp.Quit()
+		}),
+	)
+
+	desk.SetSystemTrayMenu(fyne.NewMenu("Sing-Box VPN", items...))
+	setTrayTooltip(a.trayTooltip())
+}
+
+func (a *App) trayProfileMenu() *fyne.MenuItem {
+	var items []*fyne.MenuItem
+	for _, profile := range a.profiles {
+		item := fyne.NewMenuItem(profile, func() {
+			a.handleProfileChanged(profile)
+		})
+		item.Checked = profile == a.profile
+		items = append(items, item)
+	}
+
+	menu := fyne.NewMenuItem("Profile", nil)
+	menu.ChildMenu = fyne.NewMenu("", items...)
+	return menu
+}
+
+func (a *App) trayChannelMenu(channels []string, current string) *fyne.MenuItem {
+	var items []*fyne.MenuItem
+	for _, channel := range channels {
+		item := fyne.NewMenuItem(channel, func() {
+			a.handleChannelChanged(channel)
+		})
+		item.Checked = channel == current
+		items = append(items, item)
+	}
+
+	menu := fyne.NewMenuItem("Channel", nil)
+	menu.ChildMenu = fyne.NewMenu("", items...)
+	return menu
+}
+
+// trayOutboundMenu lists the outbounds of each selector. A single selector
+// is shown directly, several get a submenu each.
+func (a *App) trayOutboundMenu(selectors []vpn.OutboundGroup) *fyne.MenuItem {
+	groupMenu := func(group vpn.OutboundGroup) *fyne.Menu {
+		var items []*fyne.MenuItem
+		for _, outbound := range group.All {
+			item := fyne.NewMenuItem(outbound, func() {
+				go a.selectOutbound(group.Name, outbound)
+			})
+			item.Checked = outbound == group.Now
+			items = append(items, item)
+		}
+		return fyne.NewMenu(group.Name, items...)
+	}
+
+	menu := fyne.NewMenuItem("Outbound", nil)
+	if len(selectors) == 1 {
+		menu.ChildMenu = groupMenu(selectors[0])
+		return menu
+	}
+
+	var groups []*fyne.MenuItem
+	for _, group := range selectors {
+		item := fyne.NewMenuItem(group.Name, nil)
+		item.ChildMenu = groupMenu(group)
+		groups = append(groups, item)
+	}
+	menu.ChildMenu = fyne.NewMenu("", groups...)
+	return menu
+}
+
+func (a *App) selectOutbound(group, outbound string) {
+	err := a.vpnController.SelectOutbound(group, outbound)
+	if err != nil {
+		a.Log("Error switching outbound: " + err.Error())
+		return
+	}
+	a.Log(fmt.Sprintf("Switched %s to %s", group, outbound))
+	a.refreshTrayOutbounds(true)
+}
+
+// trayTooltip names the connection state, subscription profile and the
+// outbound traffic currently leaves through.
+func (a *App) trayTooltip() string {
+	lines := []string{"Go Sing VPN: " + trayStateLabels[a.trayState]}
+	if a.profile != "" {
+		lines = append(lines, "Profile: "+a.profile)
+	}
+	if outbound := currentOutbound(a.trayOutbounds); outbound != "" {
+		lines = append(lines, "Outbound: "+outbound)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func selectorGroups(groups []vpn.OutboundGroup) []vpn.OutboundGroup {
+	var selectors []vpn.OutboundGroup
+	for _, group := range groups {
+		if strings.EqualFold(group.Type, "Selector") && len(group.All) > 0 {
+			selectors = append(selectors, group)
+		}
+	}
+	return selectors
+}
+
+// currentOutbound follows the first selector through nested groups, such as
+// a URL test, to the outbound in use.
+func currentOutbound(groups []vpn.OutboundGroup) string {
+	selectors := selectorGroups(groups)
+	if len(selectors) == 0 {
+		return ""
+	}
+
+	byName := map[string]vpn.OutboundGroup{}
+	for _, group := range groups {
+		byName[group.Name] = group
+	}
+
+	name := selectors[0].Now
+	for range len(groups) {
+		group, ok := byName[name]
+		if !ok || group.Now == "" {
+			break
+		}
+		name = group.Now
+	}
+	return name
+}
@@ -0,0 +1,11 @@
+//go:build !wasm && !test_web_driver
+
+package ui
+
+import "fyne.io/systray"
+
+// setTrayTooltip uses the systray library behind Fyne's tray support, as
+// Fyne itself has no tooltip API.
+func setTrayTooltip(tooltip string) {
+	systray.SetTooltip(tooltip)
+}
@@ -0,0 +1,5 @@
+//go:build wasm || test_web_driver
+
+package ui
+
+func setTrayTooltip(tooltip string) {}
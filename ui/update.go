@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"go-sing/config"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+func (a *App) showUpdateDialog(release *config.ClientRelease, required bool) {
+	if release == nil {
+		fyne.Do(func() {
+			message := fmt.Sprintf("go-sing %s is no longer supported and cannot connect.\nPlease install a newer version from your provider.", config.ClientVersion)
+			dialog.ShowInformation("Update required", message, a.window)
+		})
+		return
+	}
+
+	fyne.Do(func() {
+		notes := release.ReleaseNotes
+		if notes == "" {
+			notes = "No release notes provided."
+		}
+
+		notesText := widget.NewRichTextFromMarkdown(notes)
+		notesText.Wrapping = fyne.TextWrapWord
+		notesScroll := container.NewVScroll(notesText)
+		notesScroll.SetMinSize(fyne.NewSize(400, 200))
+
+		message := fmt.Sprintf("go-sing %s is available, you are running %s.", release.Version, config.ClientVersion)
+		title := "Update available"
+		if required {
+			message = fmt.Sprintf("go-sing %s is no longer supported and cannot connect. Please update to %s.", config.ClientVersion, release.Version)
+			title = "Update required"
+		}
+
+		content := container.NewBorder(widget.NewLabel(message), nil, nil, nil, notesScroll)
+
+		d := dialog.NewCustomWithoutButtons(title, content, a.window)
+		buttons := []fyne.CanvasObject{}
+		if !required {
+			buttons = append(buttons, widget.NewButton("Skip this version", func() {
+				d.Hide()
+				a.handleSkipUpdate(release)
+			}))
+		}
+		buttons = append(buttons,
+			widget.NewButton("Later", d.Hide),
+			widget.NewButton("Update", func() {
+				d.Hide()
+				a.handleInstallUpdate(release)
+			}),
+		)
+		d.SetButtons(buttons)
+		d.Show()
+	})
+}
+
+func (a *App) handleInstallUpdate(release *config.ClientRelease) {
+	go func() {
+		err := a.updater.Download(release)
+		if err != nil {
+			a.Log("Error updating go-sing: " + err.Error())
+		}
+	}()
+}
+
+func (a *App) handleSkipUpdate(release *config.ClientRelease) {
+	err := a.updater.Skip(release)
+	if err != nil {
+		a.Log("Error skipping update: " + err.Error())
+		return
+	}
+
+	a.Log(fmt.Sprintf("go-sing %s will not be offered again", release.Version))
+}
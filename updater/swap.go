@@ -0,0 +1,67 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	downloadSuffix = ".download"
+	pendingSuffix  = ".new"
+	oldSuffix      = ".old"
+)
+
+// ApplyPendingUpdate swaps in an executable staged by Download. Windows refuses
+// to overwrite a running executable but allows renaming it, so the current
+// binary is moved aside, the staged one takes its place and is relaunched.
+// It returns true when the caller should exit in favour of the new process.
+func ApplyPendingUpdate() (bool, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return false, fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	pendingPath := execPath + pendingSuffix
+	if _, err := os.Stat(pendingPath); err != nil {
+		go removeOldExecutable(execPath + oldSuffix)
+		return false, nil
+	}
+
+	oldPath := execPath + oldSuffix
+	os.Remove(oldPath)
+
+	err = os.Rename(execPath, oldPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to move current executable aside: %w", err)
+	}
+
+	err = os.Rename(pendingPath, execPath)
+	if err != nil {
+		if restoreErr := os.Rename(oldPath, execPath); restoreErr != nil {
+			return false, fmt.Errorf("failed to install update: %w (restore failed: %v)", err, restoreErr)
+		}
+		return false, fmt.Errorf("failed to install update: %w", err)
+	}
+
+	cmd := exec.Command(execPath, os.Args[1:]...)
+	err = cmd.Start()
+	if err != nil {
+		return false, fmt.Errorf("failed to launch updated executable: %w", err)
+	}
+
+	return true, nil
+}
+
+// removeOldExecutable deletes the binary replaced by the last update once the
+// process that used it has exited.
+func removeOldExecutable(path string) {
+	for i := 0; i < 10; i++ {
+		err := os.Remove(path)
+		if err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
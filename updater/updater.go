@@ -0,0 +1,260 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"go-sing/config"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const checkInterval = 1 * time.Hour
+
+type Logger interface {
+	Log(message string)
+}
+
+// Updater periodically compares the running client against the release
+// advertised by the delivery config and stages newer builds for next launch.
+// It also relays the delivery config's announcements.
+type Updater struct {
+	fetcher           *config.Fetcher
+	logger            Logger
+	ctx               context.Context
+	cancel            context.CancelFunc
+	mutex             sync.Mutex
+	isRunning         bool
+	notified          string
+	staged            string
+	OnUpdateAvailable func(release *config.ClientRelease, required bool)
+	OnAnnouncement    func(announcement config.Announcement)
+}
+
+func NewUpdater(fetcher *config.Fetcher, logger Logger) *Updater {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Updater{
+		fetcher: fetcher,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (u *Updater) Start() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.isRunning {
+		return
+	}
+
+	u.isRunning = true
+	go u.watchLoop()
+}
+
+func (u *Updater) Stop() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !u.isRunning {
+		return
+	}
+
+	u.cancel()
+	u.isRunning = false
+}
+
+func (u *Updater) watchLoop() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	u.check()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.check()
+		case <-u.ctx.Done():
+			return
+		}
+	}
+}
+
+func (u *Updater) check() {
+	deliveryConfig, err := u.fetcher.FetchDeliveryConfig()
+	if err != nil {
+		u.logger.Log(fmt.Sprintf("Update check: Could not fetch delivery config: %v", err))
+		// Keep enforcing the minimum version of the last verified config
+		u.checkForUpdate(nil, u.fetcher.MinClientVersion())
+		return
+	}
+
+	err = u.fetcher.StoreMinClientVersion(deliveryConfig.MinClientVersion)
+	if err != nil {
+		u.logger.Log(fmt.Sprintf("Update check: Could not store minimum client version: %v", err))
+	}
+
+	u.checkAnnouncements(deliveryConfig)
+	u.checkForUpdate(deliveryConfig.Client, deliveryConfig.MinClientVersion)
+}
+
+// checkForUpdate notifies OnUpdateAvailable once per session about a newer
+// client release. Skipped versions are not offered unless the running client
+// is below the minimum version, in which case users are told even when there
+// is no release to install, with a nil release.
+func (u *Updater) checkForUpdate(release *config.ClientRelease, minVersion string) {
+	required := config.ClientVersionBelow(minVersion)
+	if required {
+		u.logger.Log(fmt.Sprintf("go-sing %s is no longer supported, minimum version is %s", config.ClientVersion, minVersion))
+	}
+
+	if release != nil && (release.Version == "" || config.CompareVersions(release.Version, config.ClientVersion) <= 0) {
+		release = nil
+	}
+	if release != nil && release.Asset(runtime.GOOS, runtime.GOARCH) == nil {
+		u.logger.Log(fmt.Sprintf("go-sing %s has no build for %s/%s, not offering it", release.Version, runtime.GOOS, runtime.GOARCH))
+		release = nil
+	}
+	if release == nil && !required {
+		return
+	}
+
+	key := "minimum " + minVersion
+	if release != nil {
+		key = release.Version
+
+		appConfig, err := u.fetcher.LoadAppConfig()
+		if !required && err == nil && appConfig.SkippedClientVersion == release.Version {
+			return
+		}
+	}
+
+	u.mutex.Lock()
+	if u.notified == key || u.staged == key {
+		u.mutex.Unlock()
+		return
+	}
+	u.notified = key
+	callback := u.OnUpdateAvailable
+	u.mutex.Unlock()
+
+	if release != nil {
+		u.logger.Log(fmt.Sprintf("go-sing %s is available (running %s)", release.Version, config.ClientVersion))
+	}
+	if callback != nil {
+		callback(release, required)
+	}
+}
+
+// checkAnnouncements relays each active announcement once, remembering shown
+// IDs in the app config.
+func (u *Updater) checkAnnouncements(deliveryConfig *config.DeliveryConfig) {
+	announcements := deliveryConfig.ActiveAnnouncements(time.Now())
+	if len(announcements) == 0 {
+		return
+	}
+
+	appConfig, err := u.fetcher.LoadAppConfig()
+	if err != nil {
+		u.logger.Log(fmt.Sprintf("Error loading app config: %v", err))
+		return
+	}
+
+	seen := make(map[string]bool, len(appConfig.SeenAnnouncements))
+	for _, id := range appConfig.SeenAnnouncements {
+		seen[id] = true
+	}
+
+	u.mutex.Lock()
+	callback := u.OnAnnouncement
+	u.mutex.Unlock()
+
+	for _, announcement := range announcements {
+		if seen[announcement.ID] {
+			continue
+		}
+
+		u.logger.Log(fmt.Sprintf("Announcement: %s", announcement.Text))
+		if callback != nil {
+			callback(announcement)
+		}
+
+		if err := u.fetcher.MarkAnnouncementSeen(announcement.ID); err != nil {
+			u.logger.Log(fmt.Sprintf("Error saving announcement state: %v", err))
+		}
+	}
+}
+
+// Skip stops offering the given release until a newer one appears.
+func (u *Updater) Skip(release *config.ClientRelease) error {
+	return u.fetcher.SkipClientVersion(release.Version)
+}
+
+// Download fetches the release, verifies its checksum and stages it to
+// replace the running executable on next launch.
+func (u *Updater) Download(release *config.ClientRelease) error {
+	asset := release.Asset(runtime.GOOS, runtime.GOARCH)
+	if asset == nil {
+		return fmt.Errorf("release %s has no build for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	if asset.SHA256 == "" {
+		return fmt.Errorf("release %s has no checksum, refusing to install", release.Version)
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	downloadPath := execPath + downloadSuffix
+	u.logger.Log(fmt.Sprintf("Downloading go-sing %s...", release.Version))
+	err = downloadFile(asset.URL, downloadPath)
+	if err != nil {
+		os.Remove(downloadPath)
+		return fmt.Errorf("failed to download update: %w", err)
+	}
+
+	err = config.VerifyFileSHA256(downloadPath, asset.SHA256)
+	if err != nil {
+		os.Remove(downloadPath)
+		return fmt.Errorf("failed to verify update: %w", err)
+	}
+
+	err = os.Rename(downloadPath, execPath+pendingSuffix)
+	if err != nil {
+		os.Remove(downloadPath)
+		return fmt.Errorf("failed to stage update: %w", err)
+	}
+
+	u.mutex.Lock()
+	u.staged = release.Version
+	u.mutex.Unlock()
+
+	u.logger.Log(fmt.Sprintf("go-sing %s will be installed on next launch", release.Version))
+	return nil
+}
+
+func downloadFile(url, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
@@ -0,0 +1,169 @@
+package vpn
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrClashAPIDisabled is returned when the sing-box config has no
+// experimental.clash_api section to query live state from.
+var ErrClashAPIDisabled = errors.New("clash_api is not enabled in the sing-box config")
+
+// OutboundGroup is a selector or URL test outbound and the outbound it
+// currently routes through.
+type OutboundGroup struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Now  string   `json:"now"`
+	All  []string `json:"all,omitempty"`
+}
+
+type Traffic struct {
+	UploadTotal       int64 `json:"upload_total"`
+	DownloadTotal     int64 `json:"download_total"`
+	ActiveConnections int   `json:"active_connections"`
+}
+
+var clashClient = &http.Client{Timeout: 5 * time.Second}
+
+// Outbounds lists the outbound groups reported by sing-box's clash API.
+func (c *Controller) Outbounds() ([]OutboundGroup, error) {
+	var response struct {
+		Proxies map[string]struct {
+			Type string   `json:"type"`
+			Now  string   `json:"now"`
+			All  []string `json:"all"`
+		} `json:"proxies"`
+	}
+	err := c.clashGet("/proxies", &response)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []OutboundGroup{}
+	for name, proxy := range response.Proxies {
+		if proxy.Now == "" {
+			continue
+		}
+		groups = append(groups, OutboundGroup{
+			Name: name,
+			Type: proxy.Type,
+			Now:  proxy.Now,
+			All:  proxy.All,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// Traffic returns the byte counters of the running sing-box.
+func (c *Controller) Traffic() (*Traffic, error) {
+	var response struct {
+		UploadTotal   int64             `json:"uploadTotal"`
+		DownloadTotal int64             `json:"downloadTotal"`
+		Connections   []json.RawMessage `json:"connections"`
+	}
+	err := c.clashGet("/connections", &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Traffic{
+		UploadTotal:       response.UploadTotal,
+		DownloadTotal:     response.DownloadTotal,
+		ActiveConnections: len(response.Connections),
+	}, nil
+}
+
+// SelectOutbound makes a selector group route through outbound.
+func (c *Controller) SelectOutbound(group, outbound string) error {
+	body, err := json.Marshal(map[string]string{"name": outbound})
+	if err != nil {
+		return err
+	}
+	return c.clashRequest(http.MethodPut, "/proxies/"+url.PathEscape(group), bytes.NewReader(body), nil)
+}
+
+func (c *Controller) clashGet(path string, v any) error {
+	return c.clashRequest(http.MethodGet, path, nil, v)
+}
+
+func (c *Controller) clashRequest(method, path string, body io.Reader, v any) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("sing-box is not running")
+	}
+
+	controller, secret, err := c.clashAPIEndpoint()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, "http://"+controller+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+
+	resp, err := clashClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query clash API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("clash API returned HTTP %d", resp.StatusCode)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// clashAPIEndpoint reads the clash API address and secret from the sing-box config.
+func (c *Controller) clashAPIEndpoint() (controller, secret string, err error) {
+	data, err := os.ReadFile(c.paths.SingBoxConfig())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read sing-box config: %w", err)
+	}
+
+	var singBoxConfig struct {
+		Experimental struct {
+			ClashAPI *struct {
+				ExternalController string `json:"external_controller"`
+				Secret             string `json:"secret"`
+			} `json:"clash_api"`
+		} `json:"experimental"`
+	}
+	err = json.Unmarshal(data, &singBoxConfig)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse sing-box config: %w", err)
+	}
+
+	clashAPI := singBoxConfig.Experimental.ClashAPI
+	if clashAPI == nil || clashAPI.ExternalController == "" {
+		return "", "", ErrClashAPIDisabled
+	}
+
+	controller = clashAPI.ExternalController
+	if strings.HasPrefix(controller, "0.0.0.0:") || strings.HasPrefix(controller, ":") {
+		controller = "127.0.0.1:" + controller[strings.LastIndex(controller, ":")+1:]
+	}
+	return controller, clashAPI.Secret, nil
+}
@@ -3,16 +3,14 @@ package vpn
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"go-sing/config"
 	"go-sing/internal/elevation"
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"strings"
 	"sync"
-	"syscall"
 	"time"
 )
 
@@ -20,50 +18,109 @@ type Logger interface {
 	Log(message string)
 }
 
+const (
+	restartDelay       = 3 * time.Second
+	maxRestartAttempts = 5
+	// stableRunTime is how long sing-box has to stay up before its restart
+	// attempts are forgotten.
+	stableRunTime = 20 * restartDelay
+)
+
 type Controller struct {
 	isRunning        bool
 	singBoxAvailable bool
 	mutex            sync.RWMutex
-	appDir           string
+	paths            *config.Paths
 	ctx              context.Context
 	cancel           context.CancelFunc
 	logger           Logger
 	downloading      bool
 	singBoxProcess   *exec.Cmd
+	processExited    chan struct{}
 	fetcher          *config.Fetcher
 	deliveryConfig   *config.DeliveryConfig
+	channel          *config.ReleaseChannel
+	settings         config.Settings
+	intervalChanged  chan time.Duration
+	stopRequested    bool
+	restartAttempts  int
+	runningSince     time.Time
+	restartPending   bool
+	transitions      []Transition
+	transitionEvents chan Transition
+
+	// OnTransition is told about every state change, e.g. to notify the
+	// user. Set it before starting sing-box.
+	OnTransition func(Transition)
 }
 
-func rootify(p string) string {
-	if len(p) == 2 && p[1] == ':' {
-		return p + `\`
-	}
-	return p
-}
-
-func NewController(logger Logger) *Controller {
-	appDir, _ := os.Executable()
-	appDir = rootify(filepath.Dir(appDir))
-
+func NewController(logger Logger, fetcher *config.Fetcher) *Controller {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	c := &Controller{
-		appDir:  appDir,
-		ctx:     ctx,
-		cancel:  cancel,
-		logger:  logger,
-		fetcher: config.NewFetcher(),
+		paths:            fetcher.Paths(),
+		ctx:              ctx,
+		cancel:           cancel,
+		logger:           logger,
+		fetcher:          fetcher,
+		settings:         config.DefaultSettings(),
+		intervalChanged:  make(chan time.Duration, 1),
+		transitionEvents: make(chan Transition, maxTransitions),
 	}
+	go c.dispatchTransitions()
+
+	return c
+}
 
+// StartMonitoring keeps sing-box downloaded and up to date in the background.
+func (c *Controller) StartMonitoring() {
 	go c.startPeriodicCheck()
+}
 
-	return c
+// ApplySettings updates the sing-box check interval and restart policy of the
+// running controller.
+func (c *Controller) ApplySettings(settings config.Settings) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if settings.BinaryCheckInterval() != c.settings.BinaryCheckInterval() {
+		select {
+		case <-c.intervalChanged:
+		default:
+		}
+		c.intervalChanged <- settings.BinaryCheckInterval()
+	}
+	c.settings = settings
 }
 
 func (c *Controller) StartVPN() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	c.stopRequested = false
+	c.restartAttempts = 0
+	err := c.startVPN()
+	if err != nil {
+		c.recordTransition(StateStartFailed, err.Error())
+	}
+	return err
+}
+
+// RestartVPN restarts a running sing-box, e.g. to pick up a changed config.
+func (c *Controller) RestartVPN() error {
+	if !c.IsRunning() {
+		return nil
+	}
+
+	c.logger.Log("Restarting sing-box...")
+	err := c.StopVPN()
+	if err != nil {
+		return err
+	}
+	return c.StartVPN()
+}
+
+func (c *Controller) startVPN() error {
 	if c.isRunning {
 		return nil
 	}
@@ -72,29 +129,41 @@ func (c *Controller) StartVPN() error {
 		return fmt.Errorf("sing-box.exe is not available")
 	}
 
-	dataDir := filepath.Join(c.appDir, config.GoSingDataDir)
-	configPath := filepath.Join(dataDir, config.SingBoxConfigFile)
+	if minVersion := c.minClientVersion(); config.ClientVersionBelow(minVersion) {
+		return fmt.Errorf("go-sing %s is below the minimum supported version %s - please update the client", config.ClientVersion, minVersion)
+	}
+
+	configPath := c.paths.SingBoxConfig()
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return fmt.Errorf("%s not found - please update configuration first", config.SingBoxConfigFile)
 	}
 
+	c.recordTransition(StateStarting, "")
+
+	runConfig, err := config.PrepareRunConfig(c.paths, c.settings)
+	if err != nil {
+		return err
+	}
+
+	if !elevation.Supported {
+		return c.startSingBoxDirect(runConfig)
+	}
+
 	if elevation.IsGoSingElevated() {
 		c.logger.Log("Already running with admin privileges, starting sing-box directly")
-		return c.startSingBoxDirect()
+		return c.startSingBoxDirect(runConfig)
 	}
 
 	c.logger.Log("Admin privileges required, launching sing-box with elevation...")
-	return c.startSingBoxElevated()
+	return c.startSingBoxElevated(runConfig)
 }
 
-func (c *Controller) startSingBoxDirect() error {
-	dataDir := filepath.Join(c.appDir, config.GoSingDataDir)
-	singBoxPath := filepath.Join(dataDir, config.SingBoxExeName)
-	configPath := filepath.Join(dataDir, config.SingBoxConfigFile)
-	args := []string{"run", "-c", configPath, "-D", c.appDir}
+func (c *Controller) startSingBoxDirect(configPath string) error {
+	args := []string{"run", "-c", configPath, "-D", c.paths.DataDir}
 
-	c.singBoxProcess = exec.Command(singBoxPath, args...)
-	c.singBoxProcess.Dir = c.appDir
+	c.singBoxProcess = exec.Command(c.paths.SingBoxExe(), args...)
+	c.singBoxProcess.Dir = c.paths.DataDir
+	hideWindow(c.singBoxProcess)
 
 	stdout, err := c.singBoxProcess.StdoutPipe()
 	if err != nil {
@@ -116,23 +185,111 @@ func (c *Controller) startSingBoxDirect() error {
 	go c.forwardOutput(stderr, "STDERR")
 
 	c.isRunning = true
+	c.runningSince = time.Now()
+	c.recordTransition(StateRunning, fmt.Sprintf("pid %d", c.singBoxProcess.Process.Pid))
 	c.logger.Log("sing-box process started successfully")
 
-	go c.monitorProcess()
+	c.processExited = make(chan struct{})
+	go c.monitorProcess(c.singBoxProcess, c.processExited)
 
 	return nil
 }
 
-func (c *Controller) startSingBoxElevated() error {
+// StartDetached launches sing-box so that it outlives this process, writing
+// its output to the sing-box log file. Use StopAll to stop it later.
+func (c *Controller) StartDetached() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.isSingBoxProcessRunning() {
+		return fmt.Errorf("sing-box is already running")
+	}
+
+	if _, err := os.Stat(c.paths.SingBoxExe()); err != nil {
+		return fmt.Errorf("%s is not installed - run update-core first", config.SingBoxExeName)
+	}
+
+	if _, err := os.Stat(c.paths.SingBoxConfig()); os.IsNotExist(err) {
+		return fmt.Errorf("%s not found - please update configuration first", config.SingBoxConfigFile)
+	}
+
+	runConfig, err := config.PrepareRunConfig(c.paths, c.settings)
+	if err != nil {
+		return err
+	}
+
+	if elevation.Supported && !elevation.IsGoSingElevated() {
+		return elevation.LaunchSingBoxElevated(c.paths, runConfig)
+	}
+
+	err = os.MkdirAll(c.paths.LogsDir(), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	logFile, err := os.Create(c.paths.SingBoxLog())
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(c.paths.SingBoxExe(), "run", "-c", runConfig, "-D", c.paths.DataDir)
+	cmd.Dir = c.paths.DataDir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	hideWindow(cmd)
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("failed to start sing-box: %w", err)
+	}
+
+	c.logger.Log(fmt.Sprintf("sing-box started in the background (pid %d), logging to %s", cmd.Process.Pid, c.paths.SingBoxLog()))
+	return cmd.Process.Release()
+}
+
+// StopAll stops any running sing-box, including ones started by another
+// go-sing process or by StartDetached.
+func (c *Controller) StopAll() error {
+	err := c.StopVPN()
+	if err != nil {
+		return err
+	}
+
+	if !c.isSingBoxProcessRunning() {
+		return nil
+	}
+
+	if elevation.Supported && !elevation.IsGoSingElevated() {
+		c.logger.Log("Stopping elevated sing-box process...")
+		err = elevation.KillSingBoxProcessElevated()
+		if err == nil {
+			return nil
+		}
+		c.logger.Log(fmt.Sprintf("Error stopping elevated process: %v", err))
+	}
+
+	return c.killSingBoxProcess()
+}
+
+// IsSingBoxRunning reports whether any sing-box process is running, whether
+// or not this controller started it.
+func (c *Controller) IsSingBoxRunning() bool {
+	return c.isSingBoxProcessRunning()
+}
+
+func (c *Controller) startSingBoxElevated(configPath string) error {
 
-	err := elevation.LaunchSingBoxElevated(c.appDir)
+	err := elevation.LaunchSingBoxElevated(c.paths, configPath)
 	if err != nil {
 		return fmt.Errorf("failed to launch sing-box with elevation: %w", err)
 	}
 
 	c.isRunning = true
+	c.runningSince = time.Now()
+	c.recordTransition(StateRunning, "elevated")
 	c.logger.Log("sing-box launched with elevation (UAC prompt shown)")
-	c.logger.Log("Monitoring sing-box logs from: logs/sing-box.log")
+	c.logger.Log("Monitoring sing-box logs from: " + c.paths.SingBoxLog())
 
 	go c.monitorElevatedProcess()
 
@@ -152,7 +309,9 @@ func (c *Controller) monitorElevatedProcess() {
 				c.mutex.Lock()
 				if c.isRunning {
 					c.isRunning = false
+					c.recordTransition(StateExited, "elevated process stopped")
 					c.logger.Log("sing-box process has stopped")
+					c.handleUnexpectedExit(true)
 				}
 				c.mutex.Unlock()
 				return
@@ -161,37 +320,23 @@ func (c *Controller) monitorElevatedProcess() {
 	}
 }
 
-func (c *Controller) isSingBoxProcessRunning() bool {
-	cmd := exec.Command("tasklist", "/FI", "IMAGENAME eq sing-box.exe", "/FO", "CSV")
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
-	}
-
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-
-	return strings.Contains(string(output), config.SingBoxExeName)
-}
-
 func (c *Controller) StopVPN() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	c.stopRequested = true
+
 	if !c.isRunning {
 		return nil
 	}
 
 	if c.singBoxProcess != nil {
 		err := c.singBoxProcess.Process.Kill()
-		if err != nil {
+		if err != nil && !errors.Is(err, os.ErrProcessDone) {
 			c.logger.Log(fmt.Sprintf("Error killing direct process: %v", err))
 		} else {
-			err := c.singBoxProcess.Wait()
-			if err != nil {
-				return err
-			}
+			// monitorProcess owns Wait and tells us once the process is gone
+			<-c.processExited
 		}
 		c.singBoxProcess = nil
 	} else {
@@ -201,7 +346,7 @@ func (c *Controller) StopVPN() error {
 		if err != nil {
 			c.logger.Log(fmt.Sprintf("Error stopping elevated process: %v", err))
 
-			c.logger.Log("Trying regular taskkill as fallback...")
+			c.logger.Log("Trying to stop it without elevation as fallback...")
 			fallbackErr := c.killSingBoxProcess()
 			if fallbackErr != nil {
 				c.logger.Log(fmt.Sprintf("Fallback also failed: %v", fallbackErr))
@@ -211,32 +356,36 @@ func (c *Controller) StopVPN() error {
 	}
 
 	c.isRunning = false
+	c.recordTransition(StateStopped, "")
 	c.logger.Log("sing-box process stopped")
 
 	return nil
 }
 
-func (c *Controller) killSingBoxProcess() error {
-	cmd := exec.Command("taskkill", "/F", "/IM", config.SingBoxExeName)
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
-	}
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		outputStr := string(output)
-
-		if strings.Contains(outputStr, "not found") || strings.Contains(outputStr, "not running") {
+// ClientUpdateRequired reports whether the delivery config refuses connections
+// from this client version.
+func (c *Controller) ClientUpdateRequired() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return config.ClientVersionBelow(c.minClientVersion())
+}
 
-			c.logger.Log("sing-box process was not running")
-			return nil
-		}
-		c.logger.Log(fmt.Sprintf("taskkill output: %s", outputStr))
-		return fmt.Errorf("failed to kill sing-box process: %w", err)
+// minClientVersion falls back to the last verified minimum until a delivery
+// config has been fetched, so an unreachable or rejected config does not lift
+// the requirement. Called with the mutex held.
+func (c *Controller) minClientVersion() string {
+	if c.deliveryConfig != nil {
+		return c.deliveryConfig.MinClientVersion
 	}
+	return c.fetcher.MinClientVersion()
+}
 
-	c.logger.Log("Successfully terminated sing-box process")
-	return nil
+// IsRestarting reports whether sing-box exited and is about to be restarted
+// by the restart policy.
+func (c *Controller) IsRestarting() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.restartPending
 }
 
 func (c *Controller) IsRunning() bool {
@@ -256,29 +405,93 @@ func (c *Controller) forwardOutput(pipe io.ReadCloser, streamType string) {
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	// Wait closes the pipe once sing-box is gone
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
 		c.logger.Log(fmt.Sprintf("Error reading %s: %v", streamType, err))
 	}
 }
 
-func (c *Controller) monitorProcess() {
-	if c.singBoxProcess == nil {
+func (c *Controller) monitorProcess(process *exec.Cmd, exited chan struct{}) {
+	err := process.Wait()
+	close(exited)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// StopVPN already cleaned up, or a new process has been started since
+	if c.singBoxProcess != process {
 		return
 	}
 
-	err := c.singBoxProcess.Wait()
+	c.isRunning = false
+	c.singBoxProcess = nil
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	if c.stopRequested {
+		c.recordTransition(StateStopped, "")
+		c.logger.Log("sing-box process stopped")
+		return
+	}
 
 	if err != nil {
+		c.recordTransition(StateExited, err.Error())
 		c.logger.Log(fmt.Sprintf("sing-box process exited with error: %v", err))
 	} else {
+		c.recordTransition(StateExited, "")
 		c.logger.Log("sing-box process exited")
 	}
 
-	c.isRunning = false
-	c.singBoxProcess = nil
+	c.handleUnexpectedExit(err != nil)
+}
+
+// handleUnexpectedExit schedules a restart according to the restart policy
+// after sing-box exited without StopVPN. Called with the mutex held.
+func (c *Controller) handleUnexpectedExit(failed bool) {
+	switch c.settings.RestartPolicy {
+	case config.RestartAlways:
+	case config.RestartOnFailure:
+		if !failed {
+			return
+		}
+	default:
+		return
+	}
+
+	if c.stopRequested {
+		return
+	}
+
+	if time.Since(c.runningSince) >= stableRunTime {
+		c.restartAttempts = 0
+	}
+	if c.restartAttempts >= maxRestartAttempts {
+		c.recordTransition(StateGaveUp, fmt.Sprintf("%d restart attempts", c.restartAttempts))
+		c.logger.Log(fmt.Sprintf("sing-box exited %d times in a row, giving up on restarting it", c.restartAttempts))
+		return
+	}
+	c.restartAttempts++
+	c.restartPending = true
+	c.recordTransition(StateRestarting, fmt.Sprintf("attempt %d/%d", c.restartAttempts, maxRestartAttempts))
+	c.logger.Log(fmt.Sprintf("Restarting sing-box in %s (attempt %d/%d)...", restartDelay, c.restartAttempts, maxRestartAttempts))
+
+	go func() {
+		select {
+		case <-time.After(restartDelay):
+		case <-c.ctx.Done():
+			return
+		}
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		c.restartPending = false
+		if c.stopRequested || c.isRunning {
+			return
+		}
+		if err := c.startVPN(); err != nil {
+			c.recordTransition(StateStartFailed, err.Error())
+			c.logger.Log(fmt.Sprintf("Error restarting sing-box: %v", err))
+		}
+	}()
 }
 
 func (c *Controller) Stop() {
@@ -0,0 +1,257 @@
+package vpn
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-sing/config"
+	"go-sing/internal/logrotate"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	diagnosticCommandTimeout = 5 * time.Second
+	// maxDiagnosticLogSize keeps the tail of each log in the bundle.
+	maxDiagnosticLogSize = 10 << 20
+)
+
+// diagnosticCommand is a system tool whose output goes into the bundle.
+type diagnosticCommand struct {
+	file string
+	name string
+	args []string
+}
+
+// DiagnosticsFileName suggests a name for a bundle created at t.
+func DiagnosticsFileName(t time.Time) string {
+	return "go-sing-diagnostics-" + t.Format("20060102-150405") + ".zip"
+}
+
+// ExportDiagnostics writes a zip for bug reports to path: the config and app
+// config, logs, sing-box version, OS and network details and recent state
+// changes. Credentials are always masked; hideServers masks server addresses
+// as well.
+func (c *Controller) ExportDiagnostics(path string, hideServers bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create diagnostics file: %w", err)
+	}
+
+	bundle := &diagnosticsBundle{
+		zip:      zip.NewWriter(file),
+		redactor: config.NewRedactor(hideServers),
+	}
+
+	// The config goes first so the redactor knows the servers to hide in logs
+	bundle.addConfig(c.paths.SingBoxConfig())
+	bundle.addAppConfig(c.fetcher)
+	bundle.addJSON("status.json", c.Status())
+	bundle.addJSON("transitions.json", c.Transitions())
+	bundle.addCommand(diagnosticCommand{"sing-box-version.txt", c.paths.SingBoxExe(), []string{"version"}})
+	bundle.addSystemInfo()
+	for _, command := range diagnosticCommands {
+		bundle.addCommand(command)
+	}
+	bundle.addInterfaces()
+	bundle.addLog(c.paths.AppLog())
+	bundle.addLog(c.paths.SingBoxLog())
+	bundle.addErrors()
+
+	err = bundle.zip.Close()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write diagnostics file: %w", err)
+	}
+
+	c.logger.Log("Diagnostics written to " + path)
+	return nil
+}
+
+// diagnosticsBundle collects files into the zip. A part that cannot be
+// gathered is noted in errors.txt instead of failing the whole export.
+type diagnosticsBundle struct {
+	zip      *zip.Writer
+	redactor *config.Redactor
+	errors   []string
+}
+
+func (b *diagnosticsBundle) fail(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+func (b *diagnosticsBundle) add(name string, data []byte) {
+	w, err := b.zip.Create(name)
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		b.fail(name, err)
+	}
+}
+
+func (b *diagnosticsBundle) addJSON(name string, value any) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+func (b *diagnosticsBundle) addConfig(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.fail("config.json", err)
+		return
+	}
+
+	redacted, err := b.redactor.RedactConfig(data)
+	if err != nil {
+		// Not valid JSON, so there is no telling what would leak
+		b.fail("config.json", err)
+		return
+	}
+	b.add("config.json", []byte(redacted))
+}
+
+func (b *diagnosticsBundle) addAppConfig(fetcher *config.Fetcher) {
+	appConfig, err := fetcher.LoadAppConfig()
+	if err != nil {
+		b.fail("app_config.json", err)
+		return
+	}
+
+	if appConfig.SubscriptionURL != "" {
+		appConfig.SubscriptionURL = config.RedactURL(appConfig.SubscriptionURL)
+	}
+	b.addJSON("app_config.json", appConfig)
+}
+
+func (b *diagnosticsBundle) addSystemInfo() {
+	var info strings.Builder
+	fmt.Fprintf(&info, "os:       %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&info, "go:       %s\n", runtime.Version())
+	fmt.Fprintf(&info, "cpus:     %d\n", runtime.NumCPU())
+	fmt.Fprintf(&info, "time:     %s\n", time.Now().Format(time.RFC3339))
+	b.add("system/info.txt", []byte(info.String()))
+}
+
+func (b *diagnosticsBundle) addCommand(command diagnosticCommand) {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command.name, command.args...)
+	hideWindow(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil && len(output) == 0 {
+		b.fail(command.file, err)
+		return
+	}
+	b.add(command.file, []byte(b.redactor.RedactText(string(output))))
+}
+
+// addInterfaces lists network interfaces even where the system tools are
+// missing.
+func (b *diagnosticsBundle) addInterfaces() {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		b.fail("system/interfaces.txt", err)
+		return
+	}
+
+	var text strings.Builder
+	for _, iface := range interfaces {
+		fmt.Fprintf(&text, "%s mtu=%d flags=%s\n", iface.Name, iface.MTU, iface.Flags)
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			fmt.Fprintf(&text, "    %s\n", addr)
+		}
+	}
+	b.add("system/interfaces.txt", []byte(text.String()))
+}
+
+// addLog adds the current log and its newest archive, keeping only the end
+// of large files.
+func (b *diagnosticsBundle) addLog(path string) {
+	files := []string{path}
+	archives, err := logrotate.Archives(path)
+	if err == nil && len(archives) > 0 {
+		files = append(files, archives[0])
+	}
+
+	for _, file := range files {
+		name := "logs/" + strings.TrimSuffix(filepath.Base(file), ".gz")
+		data, err := readLogTail(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				b.fail(name, err)
+			}
+			continue
+		}
+		b.add(name, []byte(b.redactor.RedactText(string(data))))
+	}
+}
+
+func (b *diagnosticsBundle) addErrors() {
+	if len(b.errors) == 0 {
+		return
+	}
+	b.add("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n"))
+}
+
+// readLogTail returns the last maxDiagnosticLogSize bytes of a log,
+// decompressing gzipped archives.
+func readLogTail(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		reader = zr
+	}
+
+	var tail bytes.Buffer
+	chunk := make([]byte, 64<<10)
+	for {
+		n, err := reader.Read(chunk)
+		tail.Write(chunk[:n])
+		if tail.Len() > 2*maxDiagnosticLogSize {
+			tail.Next(tail.Len() - maxDiagnosticLogSize)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := tail.Bytes()
+	if len(data) > maxDiagnosticLogSize {
+		data = data[len(data)-maxDiagnosticLogSize:]
+	}
+	return data, nil
+}
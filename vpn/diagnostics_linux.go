@@ -0,0 +1,11 @@
+//go:build linux
+
+package vpn
+
+var diagnosticCommands = []diagnosticCommand{
+	{"system/os.txt", "uname", []string{"-a"}},
+	{"system/os-release.txt", "cat", []string{"/etc/os-release"}},
+	{"system/addresses.txt", "ip", []string{"address"}},
+	{"system/routes.txt", "ip", []string{"route", "show", "table", "all"}},
+	{"system/rules.txt", "ip", []string{"rule"}},
+}
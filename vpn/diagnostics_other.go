@@ -0,0 +1,9 @@
+//go:build !windows && !linux
+
+package vpn
+
+var diagnosticCommands = []diagnosticCommand{
+	{"system/os.txt", "uname", []string{"-a"}},
+	{"system/ifconfig.txt", "ifconfig", []string{"-a"}},
+	{"system/routes.txt", "netstat", []string{"-rn"}},
+}
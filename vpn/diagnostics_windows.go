@@ -0,0 +1,9 @@
+//go:build windows
+
+package vpn
+
+var diagnosticCommands = []diagnosticCommand{
+	{"system/os.txt", "cmd", []string{"/c", "ver"}},
+	{"system/ipconfig.txt", "ipconfig", []string{"/all"}},
+	{"system/routes.txt", "route", []string{"print"}},
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package vpn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+func hideWindow(cmd *exec.Cmd) {}
+
+// singBoxMatchArgs match only sing-box processes of the current user that
+// were started from our data directory, leaving other installs alone.
+func (c *Controller) singBoxMatchArgs() []string {
+	pattern := "^" + regexp.QuoteMeta(c.paths.SingBoxExe()) + "( |$)"
+	return []string{"-U", strconv.Itoa(os.Getuid()), "-f", pattern}
+}
+
+func (c *Controller) isSingBoxProcessRunning() bool {
+	return exec.Command("pgrep", c.singBoxMatchArgs()...).Run() == nil
+}
+
+func (c *Controller) killSingBoxProcess() error {
+	err := exec.Command("pkill", c.singBoxMatchArgs()...).Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// pkill exits with 1 when no process matched
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			c.logger.Log("sing-box process was not running")
+			return nil
+		}
+		return fmt.Errorf("failed to kill sing-box process: %w", err)
+	}
+
+	c.logger.Log("Successfully terminated sing-box process")
+	return nil
+}
@@ -0,0 +1,48 @@
+package vpn
+
+import (
+	"fmt"
+	"go-sing/config"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow: true,
+	}
+}
+
+func (c *Controller) isSingBoxProcessRunning() bool {
+	cmd := exec.Command("tasklist", "/FI", "IMAGENAME eq "+config.SingBoxExeName, "/FO", "CSV")
+	hideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(output), config.SingBoxExeName)
+}
+
+func (c *Controller) killSingBoxProcess() error {
+	cmd := exec.Command("taskkill", "/F", "/IM", config.SingBoxExeName)
+	hideWindow(cmd)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		outputStr := string(output)
+
+		if strings.Contains(outputStr, "not found") || strings.Contains(outputStr, "not running") {
+
+			c.logger.Log("sing-box process was not running")
+			return nil
+		}
+		c.logger.Log(fmt.Sprintf("taskkill output: %s", outputStr))
+		return fmt.Errorf("failed to kill sing-box process: %w", err)
+	}
+
+	c.logger.Log("Successfully terminated sing-box process")
+	return nil
+}
@@ -17,8 +17,32 @@ func (c *Controller) IsSingBoxAvailable() bool {
 	return c.singBoxAvailable
 }
 
+// CurrentChannel returns the name of the release channel sing-box is taken from.
+func (c *Controller) CurrentChannel() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.channel == nil {
+		return ""
+	}
+	return c.channel.Name
+}
+
+// AvailableChannels lists the release channels offered by the delivery config.
+func (c *Controller) AvailableChannels() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.deliveryConfig == nil {
+		return nil
+	}
+	return c.deliveryConfig.ChannelNames()
+}
+
 func (c *Controller) startPeriodicCheck() {
-	ticker := time.NewTicker(5 * time.Second)
+	c.mutex.RLock()
+	interval := c.settings.BinaryCheckInterval()
+	c.mutex.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	c.checkSingBoxFileAvailability()
@@ -27,6 +51,8 @@ func (c *Controller) startPeriodicCheck() {
 		select {
 		case <-ticker.C:
 			c.checkSingBoxFileAvailability()
+		case interval := <-c.intervalChanged:
+			ticker.Reset(interval)
 		case <-c.ctx.Done():
 			return
 		}
@@ -34,9 +60,10 @@ func (c *Controller) startPeriodicCheck() {
 }
 
 func (c *Controller) checkSingBoxFileAvailability() {
-	dataDir := filepath.Join(c.appDir, config.GoSingDataDir)
-	singBoxPath := filepath.Join(dataDir, config.SingBoxExeName)
-	singBoxExists := c.fileExists(singBoxPath)
+	singBoxExists := c.fileExists(c.paths.SingBoxExe())
+
+	// Try to fetch delivery config (optional for existing installs)
+	deliveryConfig, err := c.fetchDeliveryConfig()
 
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -45,8 +72,6 @@ func (c *Controller) checkSingBoxFileAvailability() {
 		return
 	}
 
-	// Try to fetch delivery config (optional for existing installs)
-	deliveryConfig, err := c.fetcher.FetchDeliveryConfig()
 	if err != nil {
 		c.logger.Log(fmt.Sprintf("Warning: Could not fetch delivery config: %v", err))
 		// Continue with local files if they exist
@@ -60,11 +85,11 @@ func (c *Controller) checkSingBoxFileAvailability() {
 			return
 		}
 	}
-	c.deliveryConfig = deliveryConfig
-
-	// Update stored version immediately when delivery config is fetched
-	if err := c.fetcher.UpdateSingBoxVersion(deliveryConfig.SingBoxVersion); err != nil {
-		c.logger.Log(fmt.Sprintf("Warning: Could not update version in config: %v", err))
+	err = c.useDeliveryConfig(deliveryConfig)
+	if err != nil {
+		c.logger.Log(fmt.Sprintf("Error: %v", err))
+		c.singBoxAvailable = singBoxExists
+		return
 	}
 
 	if c.isDownloadNeeded(singBoxExists) {
@@ -76,6 +101,92 @@ func (c *Controller) checkSingBoxFileAvailability() {
 	}
 }
 
+// fetchDeliveryConfig fetches the delivery config and remembers its minimum
+// client version. It goes to the network, so call it without the mutex held.
+func (c *Controller) fetchDeliveryConfig() (*config.DeliveryConfig, error) {
+	deliveryConfig, err := c.fetcher.FetchDeliveryConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.fetcher.StoreMinClientVersion(deliveryConfig.MinClientVersion)
+	if err != nil {
+		c.logger.Log(fmt.Sprintf("Warning: Could not store minimum client version: %v", err))
+	}
+	return deliveryConfig, nil
+}
+
+// useDeliveryConfig stores the delivery config and resolves the selected
+// release channel. Called with the mutex held.
+func (c *Controller) useDeliveryConfig(deliveryConfig *config.DeliveryConfig) error {
+	c.deliveryConfig = deliveryConfig
+
+	channel, err := c.fetcher.SelectedChannel(deliveryConfig)
+	if err != nil {
+		c.logger.Log(fmt.Sprintf("Warning: %v, using default channel", err))
+		channel, err = deliveryConfig.Channel("")
+		if err != nil {
+			return err
+		}
+	}
+	if c.channel == nil || c.channel.Name != channel.Name {
+		c.logger.Log(fmt.Sprintf("Using sing-box %s from %s channel", channel.SingBoxVersion, channel.Name))
+	}
+	c.channel = channel
+	return nil
+}
+
+// UpdateCore checks the delivery config and downloads sing-box when it is
+// missing or outdated, or always when force is set. It blocks until done.
+func (c *Controller) UpdateCore(force bool) error {
+	deliveryConfig, err := c.fetchDeliveryConfig()
+	if err != nil {
+		return fmt.Errorf("failed to fetch delivery config: %w", err)
+	}
+
+	singBoxExists := c.fileExists(c.paths.SingBoxExe())
+
+	c.mutex.Lock()
+	if c.downloading {
+		c.mutex.Unlock()
+		return fmt.Errorf("a sing-box download is already in progress")
+	}
+
+	err = c.useDeliveryConfig(deliveryConfig)
+	if err != nil {
+		c.mutex.Unlock()
+		return err
+	}
+
+	if !force && !c.isDownloadNeeded(singBoxExists) {
+		c.singBoxAvailable = true
+		c.mutex.Unlock()
+		c.logger.Log(fmt.Sprintf("sing-box %s is up to date", c.channel.SingBoxVersion))
+		return nil
+	}
+
+	c.singBoxAvailable = false
+	c.downloading = true
+	channel := c.channel
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		c.downloading = false
+		c.mutex.Unlock()
+	}()
+
+	err = c.installSingBox(channel)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.singBoxAvailable = true
+	c.mutex.Unlock()
+	return nil
+}
+
 func (c *Controller) fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -87,12 +198,12 @@ func (c *Controller) isDownloadNeeded(singBoxExists bool) bool {
 		return true
 	}
 
-	if c.deliveryConfig == nil {
+	if c.channel == nil {
 		c.logger.Log("No delivery config available, skipping version check")
 		return false
 	}
 
-	if versionMismatch, err := c.fetcher.CheckSingBoxVersionMismatch(c.deliveryConfig); err != nil {
+	if versionMismatch, err := c.fetcher.CheckSingBoxVersionMismatch(c.channel); err != nil {
 		c.logger.Log(fmt.Sprintf("Error checking version: %v", err))
 		return false
 	} else if versionMismatch {
@@ -110,37 +221,54 @@ func (c *Controller) downloadSingBox() {
 		c.mutex.Unlock()
 	}()
 
-	dataDir := filepath.Join(c.appDir, config.GoSingDataDir)
-	err := os.MkdirAll(dataDir, 0755)
-	if err != nil {
-		c.logger.Log(fmt.Sprintf("Error creating data directory: %v", err))
+	c.mutex.RLock()
+	channel := c.channel
+	c.mutex.RUnlock()
+
+	if channel == nil {
+		c.logger.Log("No delivery config available, cannot download")
 		return
 	}
 
-	c.logger.Log("Starting sing-box download...")
+	if err := c.installSingBox(channel); err != nil {
+		c.logger.Log(fmt.Sprintf("Error: %v", err))
+	}
+}
 
-	if c.deliveryConfig == nil {
-		c.logger.Log("No delivery config available, cannot download")
-		return
+func (c *Controller) installSingBox(channel *config.ReleaseChannel) error {
+	// sing-box runs elevated, so an unpinned zip is never installed
+	if channel.SingBoxZipSHA256 == "" {
+		return fmt.Errorf("refusing to install sing-box: the delivery config has no sing_box_zip_sha256 for the %s channel", channel.Name)
 	}
 
+	dataDir := c.paths.DataDir
+	err := os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	c.logger.Log("Starting sing-box download...")
+
 	c.logger.Log("Downloading license file...")
-	if err := c.downloadFile(c.deliveryConfig.SingBoxLicenseFile, "sing-box-license"); err != nil {
-		c.logger.Log(fmt.Sprintf("Error downloading license: %v", err))
-		return
+	if err := c.downloadFile(channel.SingBoxLicenseFile, "sing-box-license"); err != nil {
+		return fmt.Errorf("failed to download license: %w", err)
 	}
 
-	c.logger.Log("Downloading sing-box.zip...")
+	c.logger.Log(fmt.Sprintf("Downloading sing-box.zip (%s, %s channel)...", channel.SingBoxVersion, channel.Name))
 	zipPath := filepath.Join(dataDir, "sing-box.zip")
-	if err := c.downloadFile(c.deliveryConfig.SingBoxZipURL, "sing-box.zip"); err != nil {
-		c.logger.Log(fmt.Sprintf("Error downloading zip: %v", err))
-		return
+	if err := c.downloadFile(channel.SingBoxZipURL, "sing-box.zip"); err != nil {
+		return fmt.Errorf("failed to download zip: %w", err)
+	}
+
+	c.logger.Log("Verifying sing-box.zip checksum...")
+	if err := config.VerifyFileSHA256(zipPath, channel.SingBoxZipSHA256); err != nil {
+		os.Remove(zipPath)
+		return fmt.Errorf("failed to verify zip: %w", err)
 	}
 
 	c.logger.Log("Extracting sing-box.zip...")
-	if err := c.extractSingBoxFromZip(zipPath, dataDir); err != nil {
-		c.logger.Log(fmt.Sprintf("Error extracting zip: %v", err))
-		return
+	if err := c.extractSingBoxFromZip(zipPath, dataDir, channel.InArchiveExecPath); err != nil {
+		return fmt.Errorf("failed to extract zip: %w", err)
 	}
 
 	c.logger.Log("Deleting sing-box.zip...")
@@ -149,11 +277,16 @@ func (c *Controller) downloadSingBox() {
 	}
 
 	// Update the stored version in app config
-	if err := c.fetcher.UpdateSingBoxVersion(c.deliveryConfig.SingBoxVersion); err != nil {
+	if err := c.fetcher.UpdateSingBoxVersion(channel.SingBoxVersion); err != nil {
 		c.logger.Log(fmt.Sprintf("Warning: Could not update version in config: %v", err))
 	}
 
+	c.mutex.Lock()
+	c.recordTransition(StateCoreUpdated, channel.SingBoxVersion)
+	c.mutex.Unlock()
+
 	c.logger.Log("sing-box download and extraction completed")
+	return nil
 }
 
 func (c *Controller) downloadFile(url, filename string) error {
@@ -167,8 +300,7 @@ func (c *Controller) downloadFile(url, filename string) error {
 		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	dataDir := filepath.Join(c.appDir, config.GoSingDataDir)
-	filePath := filepath.Join(dataDir, filename)
+	filePath := c.paths.File(filename)
 	file, err := os.Create(filePath)
 	if err != nil {
 		return err
@@ -179,7 +311,7 @@ func (c *Controller) downloadFile(url, filename string) error {
 	return err
 }
 
-func (c *Controller) extractSingBoxFromZip(src, dest string) error {
+func (c *Controller) extractSingBoxFromZip(src, dest, inArchiveExecPath string) error {
 	reader, err := zip.OpenReader(src)
 	if err != nil {
 		return err
@@ -187,7 +319,7 @@ func (c *Controller) extractSingBoxFromZip(src, dest string) error {
 	defer reader.Close()
 
 	for _, file := range reader.File {
-		if file.Name != c.deliveryConfig.InArchiveExecPath {
+		if file.Name != inArchiveExecPath {
 			continue
 		}
 		return func() error {
@@ -212,5 +344,5 @@ func (c *Controller) extractSingBoxFromZip(src, dest string) error {
 			return nil
 		}()
 	}
-	return fmt.Errorf("sing-box.exe not found in %s", src)
+	return fmt.Errorf("%s not found in %s", inArchiveExecPath, src)
 }
@@ -0,0 +1,48 @@
+package vpn
+
+import (
+	"go-sing/config"
+)
+
+// Status is a snapshot of the client and sing-box state for the CLI and
+// other automation.
+type Status struct {
+	ClientVersion    string `json:"client_version"`
+	Running          bool   `json:"running"`
+	SingBoxInstalled bool   `json:"sing_box_installed"`
+	SingBoxVersion   string `json:"sing_box_version,omitempty"`
+	Profile          string `json:"profile,omitempty"`
+	Channel          string `json:"channel,omitempty"`
+	ConfigPresent    bool   `json:"config_present"`
+	Subscription     string `json:"subscription,omitempty"`
+	DataDir          string `json:"data_dir"`
+	Portable         bool   `json:"portable"`
+}
+
+// Status reports the current state. Running also covers sing-box processes
+// started by another go-sing process.
+func (c *Controller) Status() Status {
+	status := Status{
+		ClientVersion:    config.ClientVersion,
+		Running:          c.IsRunning() || c.isSingBoxProcessRunning(),
+		SingBoxInstalled: c.fileExists(c.paths.SingBoxExe()),
+		ConfigPresent:    c.fileExists(c.paths.SingBoxConfig()),
+		DataDir:          c.paths.DataDir,
+		Portable:         c.paths.Portable,
+		Channel:          c.CurrentChannel(),
+	}
+
+	appConfig, err := c.fetcher.LoadAppConfig()
+	if err == nil {
+		status.SingBoxVersion = appConfig.CurrentSingBoxVersion
+		status.Profile = appConfig.Profile
+		if status.Channel == "" {
+			status.Channel = appConfig.Channel
+		}
+		if appConfig.SubscriptionURL != "" {
+			status.Subscription = config.RedactURL(appConfig.SubscriptionURL)
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,59 @@
+package vpn
+
+import "time"
+
+const maxTransitions = 100
+
+// States recorded in transitions.
+const (
+	StateStarting    = "starting"
+	StateRunning     = "running"
+	StateStopped     = "stopped"
+	StateExited      = "exited"
+	StateRestarting  = "restarting"
+	StateGaveUp      = "gave up"
+	StateStartFailed = "start failed"
+	StateCoreUpdated = "core updated"
+)
+
+// Transition records a change in the state of sing-box, kept for diagnostics.
+type Transition struct {
+	Time   time.Time `json:"time"`
+	State  string    `json:"state"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// recordTransition is called with the mutex held. OnTransition is called in
+// order from a separate goroutine.
+func (c *Controller) recordTransition(state, detail string) {
+	transition := Transition{Time: time.Now(), State: state, Detail: detail}
+	if len(c.transitions) == maxTransitions {
+		c.transitions = append(c.transitions[:0], c.transitions[1:]...)
+	}
+	c.transitions = append(c.transitions, transition)
+
+	select {
+	case c.transitionEvents <- transition:
+	default:
+	}
+}
+
+// Transitions returns the most recent state changes, oldest first.
+func (c *Controller) Transitions() []Transition {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return append([]Transition{}, c.transitions...)
+}
+
+func (c *Controller) dispatchTransitions() {
+	for {
+		select {
+		case transition := <-c.transitionEvents:
+			if c.OnTransition != nil {
+				c.OnTransition(transition)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}